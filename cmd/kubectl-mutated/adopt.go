@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	adoptCmd = &cobra.Command{
+		Use:   "adopt --to MANAGER",
+		Short: "Transfer ownership of manually mutated fields to a machine manager",
+		Long: "Rewrite managed fields of resources found, merging entries of manual managers into ones of a machine manager,\n" +
+			"so the machine manager owns, and keeps or prunes, fields mutated manually going forward." + patchExitStatus,
+		Example: `  # Let kustomize-controller of Flux own fields mutated manually
+  kubectl mutated adopt --to kustomize-controller`,
+		PreRunE: cobra.NoArgs,
+		Run:     adopt,
+	}
+
+	adoptTo *string
+)
+
+func init() {
+	adoptTo = adoptCmd.Flags().String("to", "",
+		"Machine manager to transfer ownership to, which should already have an entry via the same subresource")
+	must("mark --to required", adoptCmd.MarkFlagRequired("to"))
+	addPatchFlags(adoptCmd)
+}
+
+func planAdopt(u *unstructured.Unstructured, gvk schema.GroupVersionKind) ([]string, []fieldpaths.PatchOperation, error) {
+	// not managed by the manager at all, nothing to adopt into
+	if !slices.ContainsFunc(u.GetManagedFields(), func(e metav1.ManagedFieldsEntry) bool {
+		return e.Manager == *adoptTo
+	}) {
+		return nil, nil, nil
+	}
+
+	es, adopted, err := metadata.Adopt(u, gvk.GroupKind(), *adoptTo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(adopted) == 0 {
+		return nil, nil, nil
+	}
+
+	lines := make([]string, len(adopted))
+	for i, e := range adopted {
+		lines[i] = fmt.Sprintf("%s (%s) -> %s", e.Manager, e.Operation, *adoptTo)
+		if e.Subresource != "" {
+			lines[i] += fmt.Sprintf(" via %s", e.Subresource)
+		}
+	}
+	return lines, []fieldpaths.PatchOperation{{Op: "replace", Path: "/metadata/managedFields", Value: es}}, nil
+}
+
+func adopt(_ *cobra.Command, _ []string) {
+	patchFound("adopt", planAdopt)
+}
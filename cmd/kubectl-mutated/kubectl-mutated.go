@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"maps"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"runtime/debug"
 	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,9 +18,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/cmd/get"
 
 	"github.com/xdavidwu/kubectl-mutated/internal/completion"
+	"github.com/xdavidwu/kubectl-mutated/internal/desired"
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
 	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
 	"github.com/xdavidwu/kubectl-mutated/internal/printers"
 )
@@ -30,8 +37,10 @@ type printerOption struct {
 
 var (
 	mutatedCmd = &cobra.Command{
-		Use:  "kubectl-mutated",
-		Long: "Show what resources have been mutated by a field manager that might be operated manually, like kubectl",
+		Use: "kubectl-mutated",
+		Long: "Show what resources have been mutated by a field manager that might be operated manually, like kubectl\n\n" +
+			"Exits with 1 if any resource is found with --fail-on-findings, 2 if some resources cannot be scanned or printed,\n" +
+			"or 3 on errors before scanning. Findings take precedence over skipped resources.",
 		Example: `  # List such resources under current namespace
   kubectl mutated
 
@@ -52,6 +61,29 @@ var (
 		WithAllNamespaces(false).
 		WithLabelSelector("")
 	output *string
+	outDir *string
+
+	failOnFindings *bool
+
+	printFlags         = genericclioptions.NewPrintFlags("")
+	customColumnsFlags = get.NewCustomColumnsPrintFlags()
+	managerConfig      *string
+	manualIf           *string
+	operation          *string
+
+	subresources        *[]string
+	excludeSubresources *[]string
+
+	since     *time.Duration
+	olderThan *time.Duration
+
+	fields       *[]string
+	ignoreFields *[]string
+
+	includeShared *bool
+	desiredPaths  *[]string
+	kustomizeDirs *[]string
+	helmReleases  *bool
 
 	printerOptions = map[string]printerOption{
 		"hyaml": {
@@ -72,17 +104,101 @@ var (
 				return printers.NewFilteredJSONPrinter(*rflags.AllNamespaces)
 			},
 		},
+		"diff": {
+			"Unified diff from live objects to ones with mutated fields removed",
+			func() (printers.Printer, error) {
+				return printers.NewDiffPrinter(*rflags.AllNamespaces)
+			},
+		},
+		"html": {
+			"Self-contained HTML report grouped by namespace and kind, with highlighted filtered YAML",
+			func() (printers.Printer, error) {
+				return printers.NewHTMLPrinter()
+			},
+		},
+		"json": {
+			"Mutated objects in full as a JSON List, like kubectl",
+			kubectlPrinter,
+		},
+		"yaml": {
+			"Mutated objects in full as a YAML List, like kubectl",
+			kubectlPrinter,
+		},
+		"name": {
+			"Resource names of mutated objects, like kubectl",
+			kubectlPrinter,
+		},
+		"jsonpatch": {
+			"JSON lines of objects with JSON patch removing mutated fields, for kubectl patch --type=json",
+			func() (printers.Printer, error) {
+				return printers.NewJSONPatchPrinter(*rflags.AllNamespaces)
+			},
+		},
+		"junit": {
+			"JUnit XML with a test suite per resource type and a failed test case per object, for CI",
+			func() (printers.Printer, error) {
+				return printers.NewJUnitPrinter()
+			},
+		},
+		"kustomize": {
+			"Strategic merge patches of mutated fields written into --out-dir, with a kustomize component applying them",
+			func() (printers.Printer, error) {
+				return printers.NewKustomizePrinter(*outDir, *rflags.AllNamespaces)
+			},
+		},
+		"markdown": {
+			"Markdown report grouped by namespace and kind, with per-manager counts and filtered YAML",
+			func() (printers.Printer, error) {
+				return printers.NewMarkdownPrinter()
+			},
+		},
+		"report": {
+			fmt.Sprintf("Versioned JSON report of findings, with managers and field paths, schema version %d", printers.ReportSchemaVersion),
+			func() (printers.Printer, error) {
+				return printers.NewReportPrinter()
+			},
+		},
+		"sarif": {
+			"SARIF log with a result per object and manager category, for code scanning tools",
+			func() (printers.Printer, error) {
+				return printers.NewSARIFPrinter()
+			},
+		},
+		"summary": {
+			"Tables of mutated object and field counts per namespace, GroupKind and manager, with totals scanned",
+			func() (printers.Printer, error) {
+				return printers.NewSummaryPrinter(os.Stdout)
+			},
+		},
+		"wide": {
+			"Table with mutated field paths in addition",
+			func() (printers.Printer, error) {
+				return printers.NewTablePrinter(
+					os.Stdout,
+					*rflags.AllNamespaces,
+					*includeShared,
+					hasDesiredState(),
+					true,
+				)
+			},
+		},
 		"": {
 			"Table with manual managers and mutated fields count",
 			func() (printers.Printer, error) {
-				return printers.NewTablePrinter(os.Stdout, *rflags.AllNamespaces)
+				return printers.NewTablePrinter(
+					os.Stdout,
+					*rflags.AllNamespaces,
+					*includeShared,
+					hasDesiredState(),
+					false,
+				)
 			},
 		},
 	}
 )
 
 func init() {
-	pflag := mutatedCmd.Flags()
+	pflag := mutatedCmd.PersistentFlags()
 
 	var fs flag.FlagSet
 	klog.InitFlags(&fs)
@@ -100,13 +216,59 @@ func init() {
 		popts = append(popts, disp)
 		descs = append(descs, fmt.Sprintf("%s:\t%s", disp, printerOptions[k].desc))
 	}
-	output = pflag.StringP("output", "o", "",
+	output = mutatedCmd.Flags().StringP("output", "o", "",
 		fmt.Sprintf(
-			"Output format. One of: (%s)\n%s",
+			"Output format. One of: (%s)\n%s\nOr other kubectl formats: (%s)",
 			strings.Join(popts, ", "),
 			strings.Join(descs, "\n"),
+			strings.Join(kubectlTemplateFormats(), ", "),
 		))
+	printFlags.OutputFormat = output
+	printFlags.OutputFlagSpecified = func() bool {
+		return mutatedCmd.Flag("output").Changed
+	}
+	printFlags.JSONYamlPrintFlags.AddFlags(mutatedCmd)
+	printFlags.TemplatePrinterFlags.AddFlags(mutatedCmd)
+	mutatedCmd.Flags().BoolVar(&customColumnsFlags.NoHeaders, "no-headers", false,
+		"When using custom-columns output format, don't print headers")
+	outDir = mutatedCmd.Flags().String("out-dir", "",
+		"Directory to write files into, for -o kustomize")
+	failOnFindings = mutatedCmd.Flags().Bool("fail-on-findings", false,
+		"Exit with 1 if any resource is found, for CI")
+	managerConfig = pflag.String("manager-config", "",
+		"Path to a YAML file with rules of manual managers, defaults to kubectl-mutated/config.yaml under user config directory if exists")
+	manualIf = pflag.String("manual-if", "",
+		"CEL expression deciding whether a managed fields entry is manual, overriding manualIf of manager config.\n"+
+			"Variables: entry (manager, operation, apiVersion, subresource, time if set, see has()), object (metadata), manual (decision of manager rules)")
+	operation = pflag.String("operation", "",
+		"Only consider manual managed fields entries of the operation. One of: (Apply, Update)")
+	subresources = pflag.StringSlice("subresource", nil,
+		"Only consider manual managed fields entries via the subresources, like status, scale or ephemeralcontainers")
+	excludeSubresources = pflag.StringSlice("exclude-subresource", nil,
+		"Ignore manual managed fields entries via the subresources")
+	since = pflag.Duration("since", 0,
+		"Only consider manual managed fields entries updated within the duration, like 2h")
+	olderThan = pflag.Duration("older-than", 0,
+		"Only consider manual managed fields entries last updated before the duration, like 168h")
+	fields = pflag.StringSlice("field", nil,
+		"Only consider fields matching the path patterns, and fields under them, like spec.replicas or spec.**.image.\n"+
+			"In an element, * matches any characters; ** matches any elements; list items are like containers[name=app]")
+	ignoreFields = pflag.StringSlice("ignore-field", nil,
+		"Ignore fields matching the path patterns, and fields under them, like metadata.annotations.*")
+	includeShared = pflag.Bool("include-shared", false,
+		"Also report fields co-owned by manual and machine managers, separately, with the co-owning machine managers")
+	desiredPaths = pflag.StringSliceP("desired", "f", nil,
+		"Manifest files or directories of desired state, to tell whether mutated fields stick or will be overwritten on next sync")
+	kustomizeDirs = pflag.StringSliceP("kustomize", "k", nil,
+		"Kustomization directories to render as desired state, like -f")
+	helmReleases = pflag.Bool("helm-releases", false,
+		"Use manifests of latest deployed revisions of Helm releases as desired state, to only report fields edited after release")
 	pflag.SortFlags = false
+	mutatedCmd.Flags().SortFlags = false
+
+	// completion is served by kubectl_complete-mutated instead
+	mutatedCmd.CompletionOptions.DisableDefaultCmd = true
+	mutatedCmd.AddCommand(revertCmd, adoptCmd, serveCmd)
 
 	must(
 		"register config flags completions",
@@ -117,6 +279,9 @@ func init() {
 	for k, v := range printerOptions {
 		oc = append(oc, cobra.CompletionWithDesc(k, v.desc))
 	}
+	for _, f := range kubectlTemplateFormats() {
+		oc = append(oc, f+"=")
+	}
 	must(
 		"register output flag completion",
 		mutatedCmd.RegisterFlagCompletionFunc(
@@ -125,21 +290,130 @@ func init() {
 		),
 	)
 
+	must(
+		"register operation flag completion",
+		mutatedCmd.RegisterFlagCompletionFunc(
+			"operation",
+			cobra.FixedCompletions([]cobra.Completion{
+				string(metav1.ManagedFieldsOperationApply),
+				string(metav1.ManagedFieldsOperationUpdate),
+			}, cobra.ShellCompDirectiveNoFileComp),
+		),
+	)
+
+	for _, f := range []string{"subresource", "exclude-subresource"} {
+		must(
+			fmt.Sprintf("register %s flag completion", f),
+			mutatedCmd.RegisterFlagCompletionFunc(
+				f,
+				cobra.FixedCompletions(
+					[]cobra.Completion{"status", "scale", "ephemeralcontainers", "resize"},
+					cobra.ShellCompDirectiveNoFileComp,
+				),
+			),
+		)
+	}
+
 	b, ok := debug.ReadBuildInfo()
 	if ok {
 		mutatedCmd.Version = b.Main.Version
 	}
 }
 
+// Output formats of kubectl taking an argument, like jsonpath=...
+func kubectlTemplateFormats() []string {
+	return slices.Concat(
+		printFlags.TemplatePrinterFlags.AllowedFormats(),
+		customColumnsFlags.AllowedFormats(),
+	)
+}
+
+func kubectlPrinter() (printers.Printer, error) {
+	p, err := printFlags.ToPrinter()
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		if a := printFlags.TemplatePrinterFlags.TemplateArgument; a != nil {
+			customColumnsFlags.TemplateArgument = *a
+		}
+		p, err = customColumnsFlags.ToPrinter(*output)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return printers.NewResourcePrinter(p)
+}
+
+func hasDesiredState() bool {
+	return len(*desiredPaths) != 0 || len(*kustomizeDirs) != 0 || *helmReleases
+}
+
 func must(op string, err error) {
 	if err != nil {
-		klog.Fatalf("cannot %s: %s", op, err)
+		klog.Errorf("cannot %s: %s", op, err)
+		klog.FlushAndExit(klog.ExitFlushTimeout, exitFatal)
 	}
 }
 
-func mutated(_ *cobra.Command, _ []string) {
-	dc, err := cflags.ToDiscoveryClient()
-	must("get discovery client", err)
+// What a scan did not cover due to errors
+type skipped struct {
+	groupVersions []string
+	resources     []string
+}
+
+func (s *skipped) empty() bool {
+	return len(s.groupVersions) == 0 && len(s.resources) == 0
+}
+
+// Prints a line summarizing what was skipped, if any, to stderr
+func (s *skipped) report() {
+	if s.empty() {
+		return
+	}
+	items := []string{}
+	for _, gv := range s.groupVersions {
+		items = append(items, gv+" (discovery)")
+	}
+	for _, r := range s.resources {
+		items = append(items, r+" (list)")
+	}
+	fmt.Fprintf(os.Stderr, "scan incomplete, skipped due to errors: %s\n", strings.Join(items, ", "))
+}
+
+// Applies flags to package configurations, returns namespace to scan
+func setUp() string {
+	c, err := metadata.LoadConfig(*managerConfig)
+	must("load manager config", err)
+	if *manualIf != "" {
+		must("compile --manual-if", c.SetManualIf(*manualIf))
+	}
+	metadata.SetConfig(c)
+
+	filters := []metadata.EntryFilter{}
+	switch op := metav1.ManagedFieldsOperationType(*operation); op {
+	case "":
+	case metav1.ManagedFieldsOperationApply, metav1.ManagedFieldsOperationUpdate:
+		filters = append(filters, metadata.OperationFilter(op))
+	default:
+		must("set up filters", fmt.Errorf("unrecognized operation: %s", op))
+	}
+	if len(*subresources) != 0 || len(*excludeSubresources) != 0 {
+		filters = append(filters, metadata.SubresourceFilter(*subresources, *excludeSubresources))
+	}
+	if *since != 0 || *olderThan != 0 {
+		filters = append(filters, metadata.TimeFilter(*since, *olderThan))
+	}
+	metadata.SetEntryFilters(filters...)
+
+	compile := func(ps []string) []*fieldpaths.Pattern {
+		res := make([]*fieldpaths.Pattern, len(ps))
+		for i, p := range ps {
+			var err error
+			res[i], err = fieldpaths.CompilePattern(p)
+			must("compile field pattern", err)
+		}
+		return res
+	}
+	metadata.SetFieldFilters(compile(*fields), compile(*ignoreFields))
+	metadata.SetIncludeShared(*includeShared)
 
 	// namespace may come from kubeconfig, not just cli flags
 	// this is normally hidden under ResourceBuilderFlags.ToBuilder
@@ -147,13 +421,64 @@ func mutated(_ *cobra.Command, _ []string) {
 	ns, _, err := cflags.ToRawKubeConfigLoader().Namespace()
 	must("read config", err)
 
-	opt, ok := printerOptions[*output]
-	if !ok {
-		must("set up printer", fmt.Errorf("unrecognized printer: %s", *output))
+	if hasDesiredState() {
+		d, err := loadDesiredState(ns)
+		must("load desired state", err)
+		metadata.SetDesiredState(d)
 	}
-	p, err := opt.get()
-	must("set up printer", err)
-	defer p.Flush()
+	return ns
+}
+
+// Loads desired state from -f, -k and --helm-releases
+func loadDesiredState(ns string) (*desired.State, error) {
+	d := desired.NewState()
+	for _, p := range *desiredPaths {
+		if err := d.Load(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range *kustomizeDirs {
+		if err := d.LoadKustomization(k); err != nil {
+			return nil, err
+		}
+	}
+	if *helmReleases {
+		rc, err := cflags.ToRESTConfig()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read config: %s", err)
+		}
+		client, err := kubernetes.NewForConfig(rc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create client: %s", err)
+		}
+		mapper, err := cflags.ToRESTMapper()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get REST mapper: %s", err)
+		}
+
+		hns := ns
+		if *rflags.AllNamespaces {
+			hns = ""
+		}
+		if err := d.LoadHelmReleases(context.Background(), client, hns, mapper); err != nil {
+			return nil, fmt.Errorf("cannot load helm releases: %s", err)
+		}
+	}
+	return d, nil
+}
+
+// Visits objects with manually mutated fields, of every listable resource type
+//
+// Resource types failed to discover or list are skipped and returned,
+// other errors of discovery are returned as is.
+func scan(
+	ns string,
+	configure func(*resource.Builder, schema.GroupVersionKind) *resource.Builder,
+	visit func(*resource.Info, schema.GroupVersionKind) error,
+	scanned func(schema.GroupVersionKind, int, error),
+) (*skipped, error) {
+	dc, err := cflags.ToDiscoveryClient()
+	must("get discovery client", err)
 
 	var resources []*metav1.APIResourceList
 	if *rflags.AllNamespaces {
@@ -161,7 +486,19 @@ func mutated(_ *cobra.Command, _ []string) {
 	} else {
 		resources, err = dc.ServerPreferredNamespacedResources()
 	}
-	must("perform discovery", err)
+	s := &skipped{}
+	if discovery.IsGroupDiscoveryFailedError(err) {
+		// resources of other groups are still returned
+		failed := err.(*discovery.ErrGroupDiscoveryFailed).Groups
+		for _, gv := range slices.SortedFunc(maps.Keys(failed), func(a, b schema.GroupVersion) int {
+			return strings.Compare(a.String(), b.String())
+		}) {
+			klog.Warningf("cannot discover %s: %s", gv, failed[gv])
+			s.groupVersions = append(s.groupVersions, gv.String())
+		}
+	} else if err != nil {
+		return nil, err
+	}
 
 	scheme := runtime.NewScheme()
 	must("build metav1 scheme", metav1.AddMetaToScheme(scheme))
@@ -184,7 +521,7 @@ func mutated(_ *cobra.Command, _ []string) {
 				all = false
 			}
 			// XXX QPS doesn't seem to work across builders?
-			v := p.ConfigureBuilder(resource.NewBuilder(cflags), gvk).
+			v := configure(resource.NewBuilder(cflags), gvk).
 				SelectAllParam(all).
 				NamespaceParam(ns).
 				DefaultNamespace().
@@ -197,16 +534,76 @@ func mutated(_ *cobra.Command, _ []string) {
 				ResourceTypes(fmt.Sprintf("%s.%s.%s", gvr.Resource, gvr.Version, gvr.Group)).
 				Flatten().
 				Do()
-			err = resource.NewFilteredVisitor(v, metadata.HasManuallyManagedFields).
+			n := 0
+			count := func(*resource.Info, error) (bool, error) {
+				n++
+				return true, nil
+			}
+			err = resource.NewFilteredVisitor(v, count, metadata.HasManuallyManagedFields(gvk.GroupKind())).
 				Visit(func(i *resource.Info, e error) error {
 					if e != nil {
 						return e
 					}
-					return p.PrintObject(i.Object, gvk)
+					return visit(i, gvk)
 				})
 			if err != nil {
 				klog.Warningf("cannot list %s %s: %s", rlist.GroupVersion, gvr.Resource, err)
+				s.resources = append(s.resources, gvr.GroupResource().String())
+			}
+			if scanned != nil {
+				scanned(gvk, n, err)
 			}
 		}
 	}
+	return s, nil
+}
+
+func mutated(_ *cobra.Command, _ []string) {
+	ns := setUp()
+
+	get := kubectlPrinter
+	if opt, ok := printerOptions[*output]; ok {
+		get = opt.get
+	}
+	// --template alone implies go-template, like kubectl
+	if *output == "" && *printFlags.TemplatePrinterFlags.TemplateArgument != "" {
+		get = kubectlPrinter
+	}
+	p, err := get()
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		err = fmt.Errorf("unrecognized printer: %s", *output)
+	}
+	must("set up printer", err)
+
+	var scanned func(schema.GroupVersionKind, int, error)
+	if o, ok := p.(printers.ScanObserver); ok {
+		scanned = o.ResourceTypeScanned
+	}
+	found, failed := 0, 0
+	s, err := scan(ns, p.ConfigureBuilder, func(i *resource.Info, gvk schema.GroupVersionKind) error {
+		found++
+		// not a listing error, carry on with other objects
+		if err := p.PrintObject(i.Object, gvk); err != nil {
+			klog.Warningf("cannot print %s %s/%s: %s", gvk.GroupKind(), i.Namespace, i.Name, err)
+			failed++
+		}
+		return nil
+	}, scanned)
+	must("perform discovery", err)
+	// after scanning, like resources not printed
+	flushErr := p.Flush()
+	if flushErr != nil {
+		klog.Errorf("cannot flush output: %s", flushErr)
+	}
+
+	if failed != 0 {
+		fmt.Fprintf(os.Stderr, "%d resources found cannot be printed\n", failed)
+	}
+	s.report()
+	switch {
+	case found != 0 && *failOnFindings:
+		os.Exit(exitFindings)
+	case failed != 0 || flushErr != nil || !s.empty():
+		os.Exit(exitPartial)
+	}
 }
@@ -8,9 +8,22 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
+// Exit codes
+const (
+	exitClean = iota
+	// Resources found, with --fail-on-findings
+	exitFindings
+	// Some resources not scanned, printed or patched due to errors
+	exitPartial
+	// Errors before scanning, like invalid flags
+	exitFatal
+)
+
 func main() {
 	if path.Base(os.Args[0]) == "kubectl_complete-mutated" {
 		mutatedCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, os.Args[1:]...))
 	}
-	mutatedCmd.Execute()
+	if err := mutatedCmd.Execute(); err != nil {
+		os.Exit(exitFatal)
+	}
 }
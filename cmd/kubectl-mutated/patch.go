@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+)
+
+const (
+	fieldManager = "kubectl-mutated"
+
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+
+	patchExitStatus = "\n\nExits with 2 if some resources cannot be scanned or patched, or 3 on errors before scanning."
+)
+
+var (
+	dryRun string
+	yes    bool
+)
+
+// Adds flags of subcommands patching resources found
+func addPatchFlags(c *cobra.Command) {
+	flags := c.Flags()
+	flags.StringVar(&dryRun, "dry-run", dryRunNone,
+		"Must be \"none\", \"server\", or \"client\". If client, only print what would be done. If server, submit patches without persisting them")
+	flags.BoolVarP(&yes, "yes", "y", false,
+		"Do not prompt for confirmation per resource")
+	flags.SortFlags = false
+
+	must(
+		"register dry-run flag completion",
+		c.RegisterFlagCompletionFunc(
+			"dry-run",
+			cobra.FixedCompletions(
+				[]cobra.Completion{dryRunNone, dryRunServer, dryRunClient},
+				cobra.ShellCompDirectiveNoFileComp,
+			),
+		),
+	)
+}
+
+func describe(u *unstructured.Unstructured, gvk schema.GroupVersionKind) string {
+	ref := fmt.Sprintf("%s/%s", strings.ToLower(gvk.GroupKind().String()), u.GetName())
+	if ns := u.GetNamespace(); ns != "" {
+		return fmt.Sprintf("%s in namespace %s", ref, ns)
+	}
+	return ref
+}
+
+func confirm(r *bufio.Reader, prompt string) bool {
+	fmt.Printf("%s [y/N]: ", prompt)
+	l, err := r.ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	l = strings.ToLower(strings.TrimSpace(l))
+	return l == "y" || l == "yes"
+}
+
+// Plans a JSON patch for an object, with lines describing it
+//
+// Objects with no operations planned are skipped, and counted as such if
+// described by any lines.
+type planner func(u *unstructured.Unstructured, gvk schema.GroupVersionKind) ([]string, []fieldpaths.PatchOperation, error)
+
+// Patches resources found, prompting per resource unless --yes or dry run,
+// then prints a summary, exiting with 2 if any failed or not scanned
+func patchFound(verb string, plan planner) {
+	switch dryRun {
+	case dryRunNone, dryRunClient, dryRunServer:
+	default:
+		must("set up dry run", fmt.Errorf("unrecognized dry run strategy: %s", dryRun))
+	}
+	suffix := ""
+	if dryRun != dryRunNone {
+		suffix = fmt.Sprintf(" (%s dry run)", dryRun)
+	}
+
+	ns := setUp()
+	stdin := bufio.NewReader(os.Stdin)
+	var patched, declined, skippedObjects int
+	failed := map[string]error{}
+
+	s, err := scan(
+		ns,
+		func(b *resource.Builder, _ schema.GroupVersionKind) *resource.Builder {
+			// full objects, for list indices and resourceVersion
+			return b.Unstructured()
+		},
+		func(i *resource.Info, gvk schema.GroupVersionKind) error {
+			u, ok := i.Object.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("unexpected type %T", i.Object)
+			}
+			d := describe(u, gvk)
+
+			lines, ops, err := plan(u, gvk)
+			if err != nil {
+				failed[d] = err
+				fmt.Printf("%s failed: %s\n", d, err)
+				return nil
+			}
+			if len(ops) == 0 && len(lines) == 0 {
+				return nil
+			}
+
+			fmt.Printf("%s:\n", d)
+			for _, l := range lines {
+				fmt.Printf("  %s\n", l)
+			}
+			if len(ops) == 0 {
+				fmt.Printf("%s skipped\n", d)
+				skippedObjects++
+				return nil
+			}
+			if dryRun == dryRunNone && !yes && !confirm(stdin, strings.ToUpper(verb[:1])+verb[1:]+"?") {
+				declined++
+				return nil
+			}
+
+			if dryRun != dryRunClient {
+				patch, err := json.Marshal(fieldpaths.WithResourceVersionTest(u.GetResourceVersion(), ops))
+				if err != nil {
+					failed[d] = err
+					return nil
+				}
+				_, err = resource.NewHelper(i.Client, i.Mapping).
+					DryRun(dryRun == dryRunServer).
+					WithFieldManager(fieldManager).
+					Patch(u.GetNamespace(), u.GetName(), types.JSONPatchType, patch, nil)
+				if err != nil {
+					failed[d] = err
+					fmt.Printf("%s failed: %s\n", d, err)
+					return nil
+				}
+			}
+			fmt.Printf("%s %sed%s\n", d, verb, suffix)
+			patched++
+			return nil
+		},
+		nil,
+	)
+	must("perform discovery", err)
+
+	fmt.Printf("\n%d resources %sed%s, %d declined, %d skipped, %d failed\n",
+		patched, verb, suffix, declined, skippedObjects, len(failed))
+	for d, err := range failed {
+		fmt.Printf("  %s: %s\n", d, err)
+	}
+	s.report()
+	if len(failed) != 0 || !s.empty() {
+		os.Exit(exitPartial)
+	}
+}
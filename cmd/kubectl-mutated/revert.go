@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	revertCmd = &cobra.Command{
+		Use:   "revert",
+		Short: "Remove manually mutated fields",
+		Long: "Remove fields managed solely by manual managers from resources found, with a JSON patch each.\n" +
+			"Fields to be overwritten by desired state, if any, are left as is.\n" +
+			"Fields mutated through subresources, like status, cannot be removed this way and are skipped." + patchExitStatus,
+		Example: `  # Preview what would be removed under current namespace
+  kubectl mutated revert --dry-run=server
+
+  # Remove fields mutated by kubectl edit, without confirmation
+  kubectl mutated revert --manual-if 'entry.manager == "kubectl-edit"' --yes`,
+		PreRunE: cobra.NoArgs,
+		Run:     revert,
+	}
+)
+
+func init() {
+	addPatchFlags(revertCmd)
+}
+
+// Fields mutated through subresources are skipped, as the patch is sent to the
+// main resource, which ignores changes to status, and ephemeral containers
+// cannot be removed at all.
+func planRevert(u *unstructured.Unstructured, gvk schema.GroupVersionKind) ([]string, []fieldpaths.PatchOperation, error) {
+	m, err := metadata.FindMutation(u, gvk.GroupKind())
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	bySubresource, err := m.BySubresource()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	lines := []string{}
+	var ops []fieldpaths.PatchOperation
+	if main, ok := bySubresource[""]; ok {
+		ops, err = fieldpaths.RemovalPatch(main.Sticky(), u.Object)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot locate fields: %s", err)
+		}
+		for _, op := range ops {
+			lines = append(lines, op.Op+" "+op.Path)
+		}
+	}
+	for _, sub := range slices.Sorted(maps.Keys(bySubresource)) {
+		if sub == "" {
+			continue
+		}
+		bySubresource[sub].Sticky().Iterate(func(p fieldpath.Path) {
+			lines = append(lines, fmt.Sprintf("skip %s, mutated through subresource %s", fieldpaths.Format(p), sub))
+		})
+	}
+	return lines, ops, nil
+}
+
+func revert(_ *cobra.Command, _ []string) {
+	patchFound("revert", planRevert)
+}
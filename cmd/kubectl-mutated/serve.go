@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+	"github.com/xdavidwu/kubectl-mutated/internal/printers"
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Rescan periodically and expose findings as Prometheus metrics",
+		Long: "Rescan resources on an interval and expose findings as Prometheus metrics on /metrics,\n" +
+			"like mutated_fields{namespace,group,kind,name,manager}, for alerting on drift.\n" +
+			"Only metadata of resources is listed, as in the default table output.\n" +
+			"Desired state of -f, -k and --helm-releases is reloaded for each scan.",
+		Example: `  # Expose metrics of resources of any namespaces on :9090, rescanning every 5 minutes
+  kubectl mutated serve -A --metrics-addr :9090 --interval 5m`,
+		PreRunE: cobra.NoArgs,
+		Run:     serve,
+	}
+
+	metricsAddr *string
+	interval    *time.Duration
+
+	scanDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mutated_scan_duration_seconds",
+		Help:    "Duration of scans",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	})
+	scanErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mutated_scan_errors_total",
+		Help: "Errors of scans, by stage of loading desired state, discovery, listing a resource type or printing an object",
+	}, []string{"stage"})
+	lastScan = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mutated_last_scan_timestamp_seconds",
+		Help: "Time of last finished scan",
+	})
+)
+
+func init() {
+	metricsAddr = serveCmd.Flags().String("metrics-addr", ":9090",
+		"Address to serve metrics on")
+	interval = serveCmd.Flags().Duration("interval", 5*time.Minute,
+		"Interval between starts of scans")
+}
+
+func serve(_ *cobra.Command, _ []string) {
+	ns := setUp()
+	p, err := printers.NewMetricsPrinter()
+	must("set up printer", err)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		p,
+		scanDuration,
+		scanErrors,
+		lastScan,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	// exposed even before first scan
+	scanErrors.WithLabelValues("desired")
+	scanErrors.WithLabelValues("discovery")
+	scanErrors.WithLabelValues("list")
+	scanErrors.WithLabelValues("print")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	go func() {
+		must("serve metrics", http.ListenAndServe(*metricsAddr, mux))
+	}()
+
+	dc, err := cflags.ToDiscoveryClient()
+	must("get discovery client", err)
+
+	t := time.NewTicker(*interval)
+	defer t.Stop()
+	for {
+		// pick up resource types added since last scan
+		dc.Invalidate()
+
+		start := time.Now()
+		s, err := scan(ns, p.ConfigureBuilder, func(i *resource.Info, gvk schema.GroupVersionKind) error {
+			// not a listing error, carry on with other objects
+			if err := p.PrintObject(i.Object, gvk); err != nil {
+				klog.Warningf("cannot print %s %s/%s: %s", gvk.GroupKind(), i.Namespace, i.Name, err)
+				scanErrors.WithLabelValues("print").Inc()
+			}
+			return nil
+		}, p.ResourceTypeScanned)
+		if err != nil {
+			klog.Warningf("cannot perform discovery: %s", err)
+			scanErrors.WithLabelValues("discovery").Inc()
+		} else {
+			for _, gv := range s.groupVersions {
+				gv, err := schema.ParseGroupVersion(gv)
+				must("parse GroupVersion", err)
+				p.GroupVersionSkipped(gv)
+			}
+			must("flush metrics", p.Flush())
+			scanErrors.WithLabelValues("discovery").Add(float64(len(s.groupVersions)))
+			scanErrors.WithLabelValues("list").Add(float64(len(s.resources)))
+			scanDuration.Observe(time.Since(start).Seconds())
+			lastScan.SetToCurrentTime()
+			s.report()
+		}
+
+		<-t.C
+
+		// pick up changes to manifests and helm releases since last scan,
+		// keeping previous desired state on errors
+		if hasDesiredState() {
+			d, err := loadDesiredState(ns)
+			if err != nil {
+				klog.Warningf("cannot load desired state, using previous: %s", err)
+				scanErrors.WithLabelValues("desired").Inc()
+			} else {
+				metadata.SetDesiredState(d)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package desired
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	helmStorageSelector = "owner=helm"
+	helmDeployed        = "deployed"
+
+	// set by helm on install and upgrade, absent from manifests
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	helmManagedByLabel             = "app.kubernetes.io/managed-by"
+	helmManagedBy                  = "Helm"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// Subset of helm.sh/helm/v3/pkg/release.Release we care about
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Manifest  string `json:"manifest"`
+	Info      struct {
+		Status string `json:"status"`
+	} `json:"info"`
+}
+
+// Decodes release data as stored by Helm: base64, then optionally gzip, of JSON
+func decodeHelmRelease(data string) (*helmRelease, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		b, err = io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rel := &helmRelease{}
+	if err := json.Unmarshal(b, rel); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// Adds an object as from a Helm release, see GetReleased
+func (s *State) AddReleased(u *unstructured.Unstructured) {
+	k := keyOf(u)
+	s.objects[k] = u
+	s.released[k] = true
+}
+
+func (s *State) addRelease(rel *helmRelease, mapper meta.RESTMapper) error {
+	return decode(strings.NewReader(rel.Manifest), func(u *unstructured.Unstructured) error {
+		// helm sets namespace of namespaced objects on install
+		if u.GetNamespace() == "" {
+			gvk := u.GroupVersionKind()
+			m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				klog.Warningf("cannot map %s of release %s/%s, assuming as is: %s", gvk, rel.Namespace, rel.Name, err)
+			} else if m.Scope.Name() == meta.RESTScopeNameNamespace {
+				u.SetNamespace(rel.Namespace)
+			}
+		}
+		addHelmMetadata(u, rel)
+		s.AddReleased(u)
+		return nil
+	})
+}
+
+// Adds metadata helm sets on objects of a release
+func addHelmMetadata(u *unstructured.Unstructured, rel *helmRelease) {
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[helmReleaseNameAnnotation] = rel.Name
+	annotations[helmReleaseNamespaceAnnotation] = rel.Namespace
+	u.SetAnnotations(annotations)
+
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[helmManagedByLabel] = helmManagedBy
+	u.SetLabels(labels)
+}
+
+func toUnstructured(o runtime.Object, apiVersion, kind string) (*unstructured.Unstructured, error) {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: m}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	return u, nil
+}
+
+// Loads manifests of latest deployed revisions of Helm releases, from secrets
+// and configmaps of Helm storage, under namespace or all namespaces if empty
+//
+// Storage objects themselves are also added, as they are managed by Helm.
+// Objects added are told apart by GetReleased.
+func (s *State) LoadHelmReleases(
+	ctx context.Context,
+	client kubernetes.Interface,
+	namespace string,
+	mapper meta.RESTMapper,
+) error {
+	opts := metav1.ListOptions{LabelSelector: helmStorageSelector}
+	// releases by namespace and name, data of latest deployed revision
+	latest := map[[2]string]*helmRelease{}
+
+	add := func(u *unstructured.Unstructured, data string) {
+		s.AddReleased(u)
+		if u.GetLabels()["status"] != helmDeployed {
+			return
+		}
+
+		rel, err := decodeHelmRelease(data)
+		if err != nil {
+			klog.Warningf("cannot decode helm release %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+			return
+		}
+		if rel.Namespace == "" {
+			rel.Namespace = u.GetNamespace()
+		}
+		k := [2]string{rel.Namespace, rel.Name}
+		if l, ok := latest[k]; !ok || l.Version < rel.Version {
+			latest[k] = rel
+		}
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("cannot list helm storage secrets: %s", err)
+	}
+	for _, sec := range secrets.Items {
+		u, err := toUnstructured(&sec, "v1", "Secret")
+		if err != nil {
+			return err
+		}
+		// secret data is already base64-decoded by the client
+		add(u, string(sec.Data["release"]))
+	}
+
+	cms, err := client.CoreV1().ConfigMaps(namespace).List(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("cannot list helm storage configmaps: %s", err)
+	}
+	for _, cm := range cms.Items {
+		u, err := toUnstructured(&cm, "v1", "ConfigMap")
+		if err != nil {
+			return err
+		}
+		add(u, cm.Data["release"])
+	}
+
+	for _, rel := range latest {
+		klog.V(1).Infof("loading helm release %s/%s revision %d", rel.Namespace, rel.Name, rel.Version)
+		if err := s.addRelease(rel, mapper); err != nil {
+			return fmt.Errorf("cannot decode manifest of helm release %s/%s: %s", rel.Namespace, rel.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package desired
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func encodeHelmRelease(t *testing.T, rel helmRelease) []byte {
+	j, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(j); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(b.Bytes()))
+}
+
+func helmStorageSecret(t *testing.T, manifest string, version int, status string) *corev1.Secret {
+	rel := helmRelease{Name: "app", Namespace: "prod", Version: version, Manifest: manifest}
+	rel.Info.Status = status
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.app.v%d", version),
+			Namespace: "prod",
+			Labels: map[string]string{
+				"owner":   "helm",
+				"name":    "app",
+				"version": fmt.Sprint(version),
+				"status":  status,
+			},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": encodeHelmRelease(t, rel)},
+	}
+}
+
+func TestLoadHelmReleases(t *testing.T) {
+	client := fake.NewClientset(
+		helmStorageSecret(t, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: removed
+`, 1, "superseded"),
+		helmStorageSecret(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app
+`, 2, "deployed"),
+	)
+
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	clusterRole := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deployment, meta.RESTScopeNamespace)
+	mapper.Add(clusterRole, meta.RESTScopeRoot)
+
+	s := NewState()
+	if err := s.LoadHelmReleases(t.Context(), client, "", mapper); err != nil {
+		t.Fatalf("cannot load helm releases: %s", err)
+	}
+
+	for _, tc := range []struct {
+		gk        schema.GroupKind
+		namespace string
+		name      string
+		found     bool
+	}{
+		{deployment.GroupKind(), "prod", "app", true},
+		{deployment.GroupKind(), "dev", "app", false},
+		{clusterRole.GroupKind(), "", "app", true},
+		{schema.GroupKind{Kind: "ConfigMap"}, "prod", "removed", false},
+		{schema.GroupKind{Kind: "Secret"}, "prod", "sh.helm.release.v1.app.v1", true},
+		{schema.GroupKind{Kind: "Secret"}, "prod", "sh.helm.release.v1.app.v2", true},
+	} {
+		if (s.GetReleased(tc.gk, tc.namespace, tc.name) != nil) != tc.found {
+			t.Errorf("%s %s/%s should be found as released: %v", tc.gk, tc.namespace, tc.name, tc.found)
+		}
+	}
+	u := s.GetReleased(deployment.GroupKind(), "prod", "app")
+	if u.GetAnnotations()[helmReleaseNameAnnotation] != "app" ||
+		u.GetAnnotations()[helmReleaseNamespaceAnnotation] != "prod" ||
+		u.GetLabels()[helmManagedByLabel] != helmManagedBy {
+		t.Errorf("objects of releases should have metadata set by helm, got %v, %v", u.GetAnnotations(), u.GetLabels())
+	}
+}
+
+func TestGetReleased(t *testing.T) {
+	s := NewState()
+	if err := s.Decode(bytes.NewBufferString(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`)); err != nil {
+		t.Fatalf("cannot decode: %s", err)
+	}
+
+	gk := schema.GroupKind{Kind: "ConfigMap"}
+	if s.Get(gk, "default", "config") == nil {
+		t.Errorf("object should be found")
+	}
+	if s.GetReleased(gk, "default", "config") != nil {
+		t.Errorf("object not from helm releases should not be found as released")
+	}
+}
@@ -0,0 +1,29 @@
+package desired
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Renders a kustomization directory in-process, like kubectl kustomize, and adds the results
+func (s *State) LoadKustomization(dir string) error {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	m, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return fmt.Errorf("cannot build kustomization %s: %s", dir, err)
+	}
+
+	for _, r := range m.Resources() {
+		obj, err := r.Map()
+		if err != nil {
+			return fmt.Errorf("cannot convert %s: %s", r.CurId(), err)
+		}
+		if err := s.Add(&unstructured.Unstructured{Object: obj}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
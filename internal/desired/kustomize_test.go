@@ -0,0 +1,54 @@
+package desired
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLoadKustomization(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"base/kustomization.yaml": `
+resources:
+- cm.yaml
+`,
+		"base/cm.yaml": `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  a: b
+`,
+		"overlay/kustomization.yaml": `
+namespace: prod
+namePrefix: prod-
+resources:
+- ../base
+`,
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewState()
+	if err := s.LoadKustomization(filepath.Join(dir, "overlay")); err != nil {
+		t.Fatalf("cannot load kustomization: %s", err)
+	}
+
+	u := s.Get(schema.GroupKind{Kind: "ConfigMap"}, "prod", "prod-config")
+	if u == nil {
+		t.Fatalf("rendered object should be found")
+	}
+	if u.Object["data"].(map[string]any)["a"] != "b" {
+		t.Errorf("rendered object should keep data")
+	}
+}
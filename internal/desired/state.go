@@ -0,0 +1,164 @@
+package desired
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+var (
+	manifestExtensions = []string{".yaml", ".yml", ".json"}
+)
+
+type key struct {
+	gk        schema.GroupKind
+	namespace string
+	name      string
+}
+
+// Desired state of objects, like manifests in GitOps repositories
+//
+// Objects are matched by GroupKind, namespace and name.
+// Version is ignored, as manifests may use any served version.
+type State struct {
+	objects  map[key]*unstructured.Unstructured
+	released map[key]bool
+}
+
+func NewState() *State {
+	return &State{
+		objects:  map[key]*unstructured.Unstructured{},
+		released: map[key]bool{},
+	}
+}
+
+func keyOf(u *unstructured.Unstructured) key {
+	return key{
+		gk:        u.GroupVersionKind().GroupKind(),
+		namespace: u.GetNamespace(),
+		name:      u.GetName(),
+	}
+}
+
+func eachObject(u *unstructured.Unstructured, fn func(*unstructured.Unstructured) error) error {
+	if u.IsList() {
+		return u.EachListItem(func(o runtime.Object) error {
+			i, ok := o.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("unexpected type %T", o)
+			}
+			return eachObject(i, fn)
+		})
+	}
+	return fn(u)
+}
+
+// Adds an object, or items of a list
+func (s *State) Add(u *unstructured.Unstructured) error {
+	return eachObject(u, func(u *unstructured.Unstructured) error {
+		s.objects[keyOf(u)] = u
+		return nil
+	})
+}
+
+func (s *State) lookup(gk schema.GroupKind, namespace, name string) (key, bool) {
+	k := key{gk, namespace, name}
+	if _, ok := s.objects[k]; ok {
+		return k, true
+	}
+	k.namespace = ""
+	_, ok := s.objects[k]
+	return k, ok
+}
+
+// Returns the desired object, nil if not found
+//
+// Objects without namespace in desired state also match namespaced objects,
+// as namespace is commonly set on apply.
+func (s *State) Get(gk schema.GroupKind, namespace, name string) *unstructured.Unstructured {
+	k, ok := s.lookup(gk, namespace, name)
+	if !ok {
+		return nil
+	}
+	return s.objects[k]
+}
+
+// Like Get, but only returns objects from Helm releases
+func (s *State) GetReleased(gk schema.GroupKind, namespace, name string) *unstructured.Unstructured {
+	k, ok := s.lookup(gk, namespace, name)
+	if !ok || !s.released[k] {
+		return nil
+	}
+	return s.objects[k]
+}
+
+func decode(r io.Reader, fn func(*unstructured.Unstructured) error) error {
+	d := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		obj := map[string]any{}
+		if err := d.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetKind() == "" || (u.GetName() == "" && !u.IsList()) {
+			continue
+		}
+		if err := eachObject(u, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// Decodes a stream of YAML or JSON manifests
+//
+// Documents not looking like objects, without kind or name, are skipped.
+func (s *State) Decode(r io.Reader) error {
+	return decode(r, s.Add)
+}
+
+func (s *State) loadFile(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := s.Decode(f); err != nil {
+		return fmt.Errorf("cannot decode %s: %s", p, err)
+	}
+	return nil
+}
+
+// Loads manifests from a file, or files with manifest extensions under a directory
+func (s *State) Load(p string) error {
+	st, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return s.loadFile(p)
+	}
+
+	return filepath.WalkDir(p, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !slices.Contains(manifestExtensions, filepath.Ext(p)) {
+			return nil
+		}
+		return s.loadFile(p)
+	})
+}
@@ -0,0 +1,64 @@
+package desired
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"deploy.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: prod
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config
+`,
+		"nested/svc.json": `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "app"}}`,
+		"values.yaml":     `replicas: 3`,
+		"README.md":       `# not a manifest`,
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewState()
+	if err := s.Load(dir); err != nil {
+		t.Fatalf("cannot load: %s", err)
+	}
+
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	for _, tc := range []struct {
+		gk        schema.GroupKind
+		namespace string
+		name      string
+		found     bool
+	}{
+		{deployment, "prod", "app", true},
+		{deployment, "dev", "app", false},
+		{schema.GroupKind{Kind: "ConfigMap"}, "dev", "config", true},
+		{schema.GroupKind{Kind: "Service"}, "prod", "app", true},
+		{schema.GroupKind{Kind: "Secret"}, "prod", "app", false},
+	} {
+		if (s.Get(tc.gk, tc.namespace, tc.name) != nil) != tc.found {
+			t.Errorf("%s %s/%s should be found: %v", tc.gk, tc.namespace, tc.name, tc.found)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package fieldpaths
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v6/value"
+)
+
+// Finds index of the entry in a list (as in unstructured) matching a path element
+func FindIndex(v []any, p fieldpath.PathElement) (int, error) {
+	i := -1
+	switch {
+	case p.Key != nil:
+	MapLoop:
+		for ii, vv := range v {
+			vm, ok := vv.(map[string]any)
+			if !ok {
+				return 0, fmt.Errorf("unexpected type %T", vv)
+			}
+
+			for _, f := range *p.Key {
+				c, ok := vm[f.Name]
+				if !ok {
+					continue MapLoop
+				}
+				if !value.Equals(value.NewValueInterface(c), f.Value) {
+					continue MapLoop
+				}
+			}
+			i = ii
+			break
+		}
+	case p.Value != nil:
+		for ii, vv := range v {
+			if value.Equals(value.NewValueInterface(vv), *p.Value) {
+				i = ii
+				break
+			}
+		}
+	case p.Index != nil:
+		if *p.Index < len(v) {
+			i = *p.Index
+		}
+	default:
+		return 0, fmt.Errorf("path of unexpected type: %s", p)
+	}
+	if i == -1 {
+		return 0, fmt.Errorf("no match for path: %s", p)
+	}
+	return i, nil
+}
+
+func child(v any, p fieldpath.PathElement) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		if p.FieldName == nil {
+			return nil, false
+		}
+		c, ok := v[*p.FieldName]
+		return c, ok
+	case []any:
+		i, err := FindIndex(v, p)
+		if err != nil {
+			return nil, false
+		}
+		return v[i], true
+	}
+	return nil, false
+}
+
+func partition(prefix fieldpath.Path, s *fieldpath.Set, v any, present, absent *fieldpath.Set) {
+	for p := range s.Members.All() {
+		path := append(prefix.Copy(), p)
+		if _, ok := child(v, p); ok {
+			present.Insert(path)
+		} else {
+			absent.Insert(path)
+		}
+	}
+
+	for p := range s.Children.All() {
+		path := append(prefix.Copy(), p)
+		cs := s.Children.Descend(p)
+		c, ok := child(v, p)
+		if !ok {
+			for cp := range cs.All() {
+				absent.Insert(append(path.Copy(), cp...))
+			}
+			continue
+		}
+		partition(path, cs, c, present, absent)
+	}
+}
+
+// Splits paths of a set by whether they are present in an object (as in unstructured)
+func Partition(s *fieldpath.Set, v map[string]any) (present, absent *fieldpath.Set) {
+	present, absent = &fieldpath.Set{}, &fieldpath.Set{}
+	partition(fieldpath.Path{}, s, v, present, absent)
+	return present, absent
+}
+
+func token(v any, p fieldpath.PathElement) (string, any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if p.FieldName == nil {
+			return "", nil, fmt.Errorf("path of unexpected type: %s", p)
+		}
+		c, ok := v[*p.FieldName]
+		if !ok {
+			return "", nil, fmt.Errorf("missing field: %s", p)
+		}
+		return *p.FieldName, c, nil
+	case []any:
+		i, err := FindIndex(v, p)
+		if err != nil {
+			return "", nil, err
+		}
+		return strconv.Itoa(i), v[i], nil
+	}
+	return "", nil, fmt.Errorf("unexpected type %T for path %s", v, p)
+}
+
+// Returns whether every field of a map, or every item of a list, is in the set
+func covers(s *fieldpath.Set, v any) bool {
+	covered := map[string]bool{}
+	for p := range s.Members.All() {
+		if t, _, err := token(v, p); err == nil {
+			covered[t] = true
+		}
+	}
+	for p := range s.Children.All() {
+		if t, c, err := token(v, p); err == nil && covers(s.Children.Descend(p), c) {
+			covered[t] = true
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) != 0 && len(covered) == len(v)
+	case []any:
+		return len(v) != 0 && len(covered) == len(v)
+	}
+	return false
+}
+
+func pointers(prefix []string, s *fieldpath.Set, v any, res *[][]string) error {
+	for p := range s.Members.All() {
+		t, _, err := token(v, p)
+		if err != nil {
+			return err
+		}
+		*res = append(*res, append(slices.Clone(prefix), t))
+	}
+
+	for p := range s.Children.All() {
+		t, c, err := token(v, p)
+		if err != nil {
+			return err
+		}
+		path := append(slices.Clone(prefix), t)
+		cs := s.Children.Descend(p)
+		if covers(cs, c) {
+			*res = append(*res, path)
+			continue
+		}
+		if err := pointers(path, cs, c, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compareTokens(a, b string) int {
+	ai, aerr := strconv.Atoi(a)
+	bi, berr := strconv.Atoi(b)
+	if aerr == nil && berr == nil {
+		return cmp.Compare(ai, bi)
+	}
+	return strings.Compare(a, b)
+}
+
+// Computes JSON pointers (RFC 6901) to remove paths of a set from an object
+// (as in unstructured), with list items located like FindIndex
+//
+// Maps and list items with every field in the set are removed as a whole,
+// to not leave empty ones behind. Pointers are ordered for sequential
+// removal, with later list items first.
+func RemovalPointers(s *fieldpath.Set, v map[string]any) ([]string, error) {
+	paths := [][]string{}
+	if err := pointers(nil, s, v, &paths); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(paths, func(a, b []string) int {
+		return -slices.CompareFunc(a, b, compareTokens)
+	})
+
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	res := make([]string, len(paths))
+	for i, p := range paths {
+		for j := range p {
+			p[j] = escaper.Replace(p[j])
+		}
+		res[i] = "/" + strings.Join(p, "/")
+	}
+	return res, nil
+}
+
+// Operation of JSON patch (RFC 6902)
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Computes a JSON patch removing paths of a set from an object (as in unstructured),
+// see RemovalPointers
+func RemovalPatch(s *fieldpath.Set, v map[string]any) ([]PatchOperation, error) {
+	ps, err := RemovalPointers(s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]PatchOperation, len(ps))
+	for i, p := range ps {
+		ops[i] = PatchOperation{Op: "remove", Path: p}
+	}
+	return ops, nil
+}
+
+// Prepends a test of resourceVersion to a JSON patch, so it is rejected once
+// the object has changed, instead of removing whatever list items have shifted
+// to the same indices
+func WithResourceVersionTest(rv string, ops []PatchOperation) []PatchOperation {
+	return append([]PatchOperation{{
+		Op:    "test",
+		Path:  "/metadata/resourceVersion",
+		Value: rv,
+	}}, ops...)
+}
+
+func formatValue(v value.Value) string {
+	if v.IsString() {
+		return v.AsString()
+	}
+	return value.ToString(v)
+}
+
+// Formats a path element, like name, [name=app], [=foo], or [0]
+func formatElement(e fieldpath.PathElement) string {
+	switch {
+	case e.FieldName != nil:
+		return *e.FieldName
+	case e.Key != nil:
+		kvs := make([]string, len(*e.Key))
+		for i, f := range *e.Key {
+			kvs[i] = f.Name + "=" + formatValue(f.Value)
+		}
+		return "[" + strings.Join(kvs, ",") + "]"
+	case e.Value != nil:
+		return "[=" + formatValue(*e.Value) + "]"
+	case e.Index != nil:
+		return fmt.Sprintf("[%d]", *e.Index)
+	}
+	return ""
+}
+
+// Formats a path for humans, like spec.template.spec.containers[name=app].image
+//
+// Unlike fieldpath.Path.String(), string values are not quoted,
+// thus ambiguous in rare cases.
+func Format(p fieldpath.Path) string {
+	var b strings.Builder
+	for _, e := range p {
+		if e.FieldName != nil && b.Len() != 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(formatElement(e))
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package fieldpaths
+
+import (
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v6/value"
+)
+
+func TestPartition(t *testing.T) {
+	v := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"app": "test"},
+		},
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"name": "app", "image": "alpine"},
+			},
+			"finalizers": []any{"a"},
+		},
+	}
+
+	app := fieldpath.KeyByFields("name", "app")
+	sidecar := fieldpath.KeyByFields("name", "sidecar")
+	s := fieldpath.NewSet(
+		fieldpath.MakePathOrDie("metadata", "labels", "app"),
+		fieldpath.MakePathOrDie("metadata", "labels", "foo"),
+		fieldpath.MakePathOrDie("metadata", "annotations", "foo"),
+		fieldpath.MakePathOrDie("spec", "containers", app, "image"),
+		fieldpath.MakePathOrDie("spec", "containers", app, "env"),
+		fieldpath.MakePathOrDie("spec", "containers", sidecar, "image"),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("a")),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("b")),
+	)
+
+	present, absent := Partition(s, v)
+	for _, p := range []fieldpath.Path{
+		fieldpath.MakePathOrDie("metadata", "labels", "app"),
+		fieldpath.MakePathOrDie("spec", "containers", app, "image"),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("a")),
+	} {
+		if !present.Has(p) || absent.Has(p) {
+			t.Errorf("%s should be present", p)
+		}
+	}
+	for _, p := range []fieldpath.Path{
+		fieldpath.MakePathOrDie("metadata", "labels", "foo"),
+		fieldpath.MakePathOrDie("metadata", "annotations", "foo"),
+		fieldpath.MakePathOrDie("spec", "containers", app, "env"),
+		fieldpath.MakePathOrDie("spec", "containers", sidecar, "image"),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("b")),
+	} {
+		if present.Has(p) || !absent.Has(p) {
+			t.Errorf("%s should be absent", p)
+		}
+	}
+}
+
+func TestRemovalPointers(t *testing.T) {
+	v := map[string]any{
+		"metadata": map[string]any{
+			"labels":      map[string]any{"app": "test", "foo": "bar"},
+			"annotations": map[string]any{"a/b": "c"},
+		},
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"name": "app", "image": "alpine", "tty": true},
+				map[string]any{"name": "debug", "image": "busybox"},
+				map[string]any{"name": "sidecar", "image": "envoy"},
+			},
+			"finalizers": []any{"a", "b", "c"},
+		},
+	}
+
+	s := fieldpath.NewSet(
+		fieldpath.MakePathOrDie("metadata", "labels", "foo"),
+		fieldpath.MakePathOrDie("metadata", "annotations", "a/b"),
+		fieldpath.MakePathOrDie("spec", "containers", fieldpath.KeyByFields("name", "app"), "tty"),
+		fieldpath.MakePathOrDie("spec", "containers", fieldpath.KeyByFields("name", "debug"), "name"),
+		fieldpath.MakePathOrDie("spec", "containers", fieldpath.KeyByFields("name", "debug"), "image"),
+		fieldpath.MakePathOrDie("spec", "containers", fieldpath.KeyByFields("name", "sidecar"), "image"),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("a")),
+		fieldpath.MakePathOrDie("spec", "finalizers", value.NewValueInterface("c")),
+	)
+
+	ps, err := RemovalPointers(s, v)
+	if err != nil {
+		t.Fatalf("cannot compute pointers: %s", err)
+	}
+	expected := []string{
+		"/spec/finalizers/2",
+		"/spec/finalizers/0",
+		"/spec/containers/2/image",
+		"/spec/containers/1",
+		"/spec/containers/0/tty",
+		"/metadata/labels/foo",
+		"/metadata/annotations",
+	}
+	if !slices.Equal(ps, expected) {
+		t.Errorf("expected %v, got %v", expected, ps)
+	}
+
+	s.Insert(fieldpath.MakePathOrDie("spec", "volumes"))
+	if _, err := RemovalPointers(s, v); err == nil {
+		t.Errorf("missing field should fail")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		path     fieldpath.Path
+		expected string
+	}{
+		{
+			fieldpath.MakePathOrDie("spec", "template", "spec", "containers", fieldpath.KeyByFields("name", "app"), "image"),
+			"spec.template.spec.containers[name=app].image",
+		},
+		{
+			fieldpath.MakePathOrDie("spec", "ports", fieldpath.KeyByFields("containerPort", 80, "protocol", "TCP")),
+			"spec.ports[containerPort=80,protocol=TCP]",
+		},
+		{
+			fieldpath.MakePathOrDie("metadata", "finalizers", value.NewValueInterface("foo")),
+			"metadata.finalizers[=foo]",
+		},
+		{
+			fieldpath.MakePathOrDie("spec", "args", 1),
+			"spec.args[1]",
+		},
+	} {
+		if s := Format(tc.path); s != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, s)
+		}
+	}
+}
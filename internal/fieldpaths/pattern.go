@@ -0,0 +1,148 @@
+package fieldpaths
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+)
+
+// Pattern of paths, in the form of Format, with wildcards
+//
+// Elements are separated by dots, or start with brackets for list items.
+// In an element, * matches any characters, and ? matches one.
+// An element of ** matches any number of elements.
+// Backslash escapes the next character, like dots in annotation keys.
+// A pattern matches paths under paths it matches, too.
+//
+// For example, metadata.annotations.*, spec.**.image,
+// spec.containers[name=app].env, or metadata.labels.app\.kubernetes\.io/name.
+type Pattern struct {
+	raw string
+	// nil for **
+	elements []*regexp.Regexp
+}
+
+func CompilePattern(s string) (*Pattern, error) {
+	p := &Pattern{raw: s}
+
+	var re strings.Builder
+	var raw strings.Builder
+	inBracket := false
+	// whether an element is expected, after a dot
+	expecting := false
+	end := func() error {
+		if raw.Len() == 0 {
+			return fmt.Errorf("empty element in pattern %q", s)
+		}
+		if raw.String() == "**" {
+			p.elements = append(p.elements, nil)
+		} else {
+			r, err := regexp.Compile("^" + re.String() + "$")
+			if err != nil {
+				return err
+			}
+			p.elements = append(p.elements, r)
+		}
+		re.Reset()
+		raw.Reset()
+		return nil
+	}
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		expecting = false
+		switch {
+		case c == '\\':
+			if i+1 == len(rs) {
+				return nil, fmt.Errorf("trailing backslash in pattern %q", s)
+			}
+			i++
+			re.WriteString(regexp.QuoteMeta(string(rs[i])))
+			// not to be taken as **
+			raw.WriteString("\\" + string(rs[i]))
+			continue
+		case c == '.' && !inBracket:
+			if err := end(); err != nil {
+				return nil, err
+			}
+			expecting = true
+			continue
+		case c == '[' && !inBracket:
+			// list items start an element, without a dot
+			if raw.Len() != 0 {
+				if err := end(); err != nil {
+					return nil, err
+				}
+			}
+			inBracket = true
+		case c == ']' && inBracket:
+			inBracket = false
+			re.WriteString(`\]`)
+			raw.WriteRune(c)
+			if err := end(); err != nil {
+				return nil, err
+			}
+			// dot after list item is optional
+			if i+1 < len(rs) && rs[i+1] == '.' {
+				i++
+			}
+			continue
+		}
+
+		switch c {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+		raw.WriteRune(c)
+	}
+	if inBracket {
+		return nil, fmt.Errorf("unclosed bracket in pattern %q", s)
+	}
+	if raw.Len() != 0 || len(p.elements) == 0 || expecting {
+		if err := end(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func matchElements(ps []*regexp.Regexp, es []string) bool {
+	if len(ps) == 0 {
+		return len(es) == 0
+	}
+	if ps[0] == nil {
+		for i := 0; i <= len(es); i++ {
+			if matchElements(ps[1:], es[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(es) != 0 && ps[0].MatchString(es[0]) && matchElements(ps[1:], es[1:])
+}
+
+// Returns whether the pattern matches the path or any of its parents
+func (p *Pattern) Matches(path fieldpath.Path) bool {
+	es := make([]string, len(path))
+	for i, e := range path {
+		es[i] = formatElement(e)
+	}
+
+	for i := 1; i <= len(es); i++ {
+		if matchElements(p.elements, es[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pattern) String() string {
+	return p.raw
+}
@@ -0,0 +1,57 @@
+package fieldpaths
+
+import (
+	"testing"
+
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v6/value"
+)
+
+func TestPattern(t *testing.T) {
+	image := fieldpath.MakePathOrDie("spec", "template", "spec", "containers", fieldpath.KeyByFields("name", "app"), "image")
+	replicas := fieldpath.MakePathOrDie("spec", "replicas")
+	annotation := fieldpath.MakePathOrDie("metadata", "annotations", "app.kubernetes.io/name")
+	finalizer := fieldpath.MakePathOrDie("metadata", "finalizers", value.NewValueInterface("foo"))
+
+	for _, tc := range []struct {
+		pattern string
+		matched []fieldpath.Path
+		missed  []fieldpath.Path
+	}{
+		{"spec.replicas", []fieldpath.Path{replicas}, []fieldpath.Path{image}},
+		{"spec", []fieldpath.Path{replicas, image}, []fieldpath.Path{annotation}},
+		{"spec.**.image", []fieldpath.Path{image}, []fieldpath.Path{replicas}},
+		{"**.image", []fieldpath.Path{image}, []fieldpath.Path{replicas}},
+		{"metadata.annotations.*", []fieldpath.Path{annotation}, []fieldpath.Path{finalizer}},
+		{`metadata.annotations.app\.kubernetes\.io/*`, []fieldpath.Path{annotation}, nil},
+		{"metadata.annotations.app", nil, []fieldpath.Path{annotation}},
+		{"spec.template.spec.containers[name=app].image", []fieldpath.Path{image}, nil},
+		{"spec.template.spec.containers[name=sidecar]", nil, []fieldpath.Path{image}},
+		{"spec.*.spec.containers[*]", []fieldpath.Path{image}, nil},
+		{"metadata.finalizers[=f*]", []fieldpath.Path{finalizer}, nil},
+		{"*", []fieldpath.Path{replicas, image, annotation}, nil},
+	} {
+		p, err := CompilePattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("cannot compile %s: %s", tc.pattern, err)
+		}
+		for _, path := range tc.matched {
+			if !p.Matches(path) {
+				t.Errorf("%s should match %s", tc.pattern, path)
+			}
+		}
+		for _, path := range tc.missed {
+			if p.Matches(path) {
+				t.Errorf("%s should not match %s", tc.pattern, path)
+			}
+		}
+	}
+}
+
+func TestPatternInvalid(t *testing.T) {
+	for _, s := range []string{"", "spec..replicas", ".spec", "spec.", "spec.containers[name=app", `spec\`} {
+		if _, err := CompilePattern(s); err == nil {
+			t.Errorf("%q should be invalid", s)
+		}
+	}
+}
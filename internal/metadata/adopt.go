@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+)
+
+// Finds the entry of a manager to adopt fields via a subresource, preferring Apply
+func adopterIndex(es []metav1.ManagedFieldsEntry, manager, subresource string) int {
+	i := -1
+	for j, e := range es {
+		if e.Manager != manager || e.Subresource != subresource {
+			continue
+		}
+		if i == -1 || e.Operation == metav1.ManagedFieldsOperationApply {
+			i = j
+		}
+	}
+	return i
+}
+
+func sameEntry(a, b metav1.ManagedFieldsEntry) bool {
+	return a.Manager == b.Manager && a.Operation == b.Operation &&
+		a.Subresource == b.Subresource && a.APIVersion == b.APIVersion
+}
+
+func parseFields(e metav1.ManagedFieldsEntry) (*fieldpath.Set, error) {
+	s := &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw)); err != nil {
+		return nil, fmt.Errorf("invalid fields of manager %s: %s", e.Manager, err)
+	}
+	return s, nil
+}
+
+// Computes managed fields with fields of entries found by FindSoleManualManagers
+// moved into ones of a machine manager, like how Flux revokes kubectl ownership
+//
+// Like FindMutation, fields of helm entries set by a Helm release in desired
+// state are not adopted.
+//
+// Fields of each manual entry go to the entry of the manager via the same
+// subresource, which is expected to exist. Manual entries left without fields
+// are removed. Returns the new managed fields and the manual entries adopted.
+func Adopt(o metav1.Object, gk schema.GroupKind, to string) ([]metav1.ManagedFieldsEntry, []metav1.ManagedFieldsEntry, error) {
+	es := slices.Clone(o.GetManagedFields())
+	manual, machine := classify(o, releasedObject(o, gk))
+	adopted := soleAmong(manual, systemManagedSet(machine))
+	sets := map[int]*fieldpath.Set{}
+
+	for _, a := range adopted {
+		i := adopterIndex(es, to, a.Subresource)
+		if i == -1 {
+			return nil, nil, fmt.Errorf("no entry of manager %s via subresource %q", to, a.Subresource)
+		}
+		if IsManualManager(o, es[i]) {
+			return nil, nil, fmt.Errorf("manager %s is manual", to)
+		}
+		j := slices.IndexFunc(es, func(e metav1.ManagedFieldsEntry) bool {
+			return sameEntry(a, e)
+		})
+		if j == -1 {
+			return nil, nil, fmt.Errorf("entry of manager %s not found", a.Manager)
+		}
+
+		s, err := parseFields(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, k := range []int{i, j} {
+			if _, ok := sets[k]; !ok {
+				if sets[k], err = parseFields(es[k]); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		sets[i] = sets[i].Union(s)
+		sets[j] = sets[j].Difference(s)
+	}
+
+	removed := map[int]bool{}
+	for i, s := range sets {
+		if s.Empty() {
+			removed[i] = true
+			continue
+		}
+		b, err := s.ToJSON()
+		if err != nil {
+			return nil, nil, err
+		}
+		es[i].FieldsV1 = &metav1.FieldsV1{Raw: b}
+	}
+
+	res := []metav1.ManagedFieldsEntry{}
+	for i, e := range es {
+		if !removed[i] {
+			res = append(res, e)
+		}
+	}
+	return res, adopted, nil
+}
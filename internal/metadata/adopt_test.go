@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/desired"
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+)
+
+func TestAdopt(t *testing.T) {
+	o := &metav1.ObjectMeta{
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   machineFieldManager,
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)},
+			},
+			{
+				Manager:   "kubectl-label",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:foo":{}}}}`)},
+			},
+			{
+				Manager:   "kube-controller-manager",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:phase":{}}}`)},
+			},
+		},
+	}
+
+	es, adopted, err := Adopt(o, podGK, machineFieldManager)
+	if err != nil {
+		t.Fatalf("cannot adopt: %s", err)
+	}
+	if len(adopted) != 1 || adopted[0].Manager != "kubectl-label" {
+		t.Fatalf("should adopt the manual entry, got %v", adopted)
+	}
+	if len(es) != 2 || es[0].Manager != machineFieldManager || es[1].Manager != "kube-controller-manager" {
+		t.Fatalf("manual entry should be removed, got %v", es)
+	}
+
+	s := &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(es[0].FieldsV1.Raw)); err != nil {
+		t.Fatalf("invalid fields: %s", err)
+	}
+	assertSetHasPath(t, s, "metadata", "labels", "app")
+	assertSetHasPath(t, s, "metadata", "labels", "foo")
+
+	if _, _, err := Adopt(o, podGK, "flux"); err == nil {
+		t.Errorf("adopting to a manager without entry should fail")
+	}
+	if _, _, err := Adopt(o, podGK, "kubectl-label"); err == nil {
+		t.Errorf("adopting to a manual manager should fail")
+	}
+}
+
+func TestAdoptSelectedFields(t *testing.T) {
+	include, err := fieldpaths.CompilePattern("metadata.labels.foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetFieldFilters([]*fieldpaths.Pattern{include}, nil)
+	t.Cleanup(func() { SetFieldFilters(nil, nil) })
+
+	o := &metav1.ObjectMeta{
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   machineFieldManager,
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)},
+			},
+			{
+				Manager:   "kubectl-label",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:foo":{},"f:bar":{}}}}`)},
+			},
+		},
+	}
+
+	es, _, err := Adopt(o, podGK, machineFieldManager)
+	if err != nil {
+		t.Fatalf("cannot adopt: %s", err)
+	}
+	if len(es) != 2 {
+		t.Fatalf("manual entry with fields not selected should be kept, got %v", es)
+	}
+
+	s := &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(es[0].FieldsV1.Raw)); err != nil {
+		t.Fatalf("invalid fields: %s", err)
+	}
+	assertSetHasPath(t, s, "metadata", "labels", "foo")
+	assertSetNotHasPath(t, s, "metadata", "labels", "bar")
+
+	s = &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(es[1].FieldsV1.Raw)); err != nil {
+		t.Fatalf("invalid fields: %s", err)
+	}
+	assertSetHasPath(t, s, "metadata", "labels", "bar")
+	assertSetNotHasPath(t, s, "metadata", "labels", "foo")
+}
+
+func TestAdoptHelmRelease(t *testing.T) {
+	t.Cleanup(func() { SetDesiredState(nil) })
+
+	d := desired.NewState()
+	d.AddReleased(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":      "test",
+			"namespace": "default",
+			"labels":    map[string]any{"app": "test"},
+		},
+	}})
+	SetDesiredState(d)
+
+	o := &metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "default",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   machineFieldManager,
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+			},
+			{
+				Manager:   "helm",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{},"f:foo":{}}}}`)},
+			},
+		},
+	}
+
+	es, _, err := Adopt(o, podGK, machineFieldManager)
+	if err != nil {
+		t.Fatalf("cannot adopt: %s", err)
+	}
+	if len(es) != 2 {
+		t.Fatalf("helm entry with fields set by release should be kept, got %v", es)
+	}
+
+	s := &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(es[0].FieldsV1.Raw)); err != nil {
+		t.Fatalf("invalid fields: %s", err)
+	}
+	assertSetHasPath(t, s, "metadata", "labels", "foo")
+	assertSetNotHasPath(t, s, "metadata", "labels", "app")
+
+	s = &fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(es[1].FieldsV1.Raw)); err != nil {
+		t.Fatalf("invalid fields: %s", err)
+	}
+	assertSetHasPath(t, s, "metadata", "labels", "app")
+	assertSetNotHasPath(t, s, "metadata", "labels", "foo")
+}
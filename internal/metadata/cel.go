@@ -0,0 +1,102 @@
+package metadata
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	celEnv = func() *cel.Env {
+		env, err := cel.NewEnv(
+			cel.Variable("entry", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("manual", cel.BoolType),
+			ext.Strings(),
+		)
+		if err != nil {
+			panic(fmt.Errorf("cannot build CEL environment: %s", err))
+		}
+		return env
+	}()
+)
+
+// Compiles a CEL expression deciding whether a managed fields entry is manual
+//
+// Variables available:
+//   - entry: the managed fields entry, with manager, operation, apiVersion,
+//     subresource, and time if set, thus to be guarded by has(entry.time)
+//   - object: metadata of the object, with name, namespace, generateName,
+//     uid, labels, annotations, finalizers, and creationTimestamp
+//   - manual: the decision of manager rules and exemptions
+func compileManualIf(expr string) (cel.Program, error) {
+	ast, iss := celEnv.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+
+	if !reflect.DeepEqual(ast.OutputType(), cel.BoolType) {
+		return nil, fmt.Errorf("expression should evaluate to bool, got %s", ast.OutputType())
+	}
+
+	return celEnv.Program(ast)
+}
+
+func entryToCEL(e metav1.ManagedFieldsEntry) map[string]any {
+	m := map[string]any{
+		"manager":     e.Manager,
+		"operation":   string(e.Operation),
+		"apiVersion":  e.APIVersion,
+		"subresource": e.Subresource,
+	}
+	if e.Time != nil {
+		m["time"] = e.Time.Time
+	}
+	return m
+}
+
+func objectToCEL(o metav1.Object) map[string]any {
+	labels := o.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	finalizers := o.GetFinalizers()
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+
+	return map[string]any{
+		"name":              o.GetName(),
+		"namespace":         o.GetNamespace(),
+		"generateName":      o.GetGenerateName(),
+		"uid":               string(o.GetUID()),
+		"labels":            labels,
+		"annotations":       annotations,
+		"finalizers":        finalizers,
+		"creationTimestamp": o.GetCreationTimestamp().Time,
+	}
+}
+
+func evalManualIf(p cel.Program, o metav1.Object, e metav1.ManagedFieldsEntry, manual bool) (bool, error) {
+	v, _, err := p.Eval(map[string]any{
+		"entry":  entryToCEL(e),
+		"object": objectToCEL(o),
+		"manual": manual,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected result type %T", v.Value())
+	}
+	return b, nil
+}
@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManualIf(t *testing.T) {
+	t.Cleanup(func() { SetConfig(mustParseConfig(defaultConfig)) })
+
+	c := mustParseConfig(defaultConfig)
+	err := c.SetManualIf(`manual && !(entry.operation == 'Apply' && object.namespace.startsWith('ci-'))`)
+	if err != nil {
+		t.Fatalf("cannot compile expression: %s", err)
+	}
+	SetConfig(c)
+
+	for _, tc := range []struct {
+		namespace string
+		entry     metav1.ManagedFieldsEntry
+		expected  bool
+	}{
+		{"ci-1", metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}, false},
+		{"ci-1", metav1.ManagedFieldsEntry{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate}, true},
+		{"prod", metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}, true},
+		{"prod", metav1.ManagedFieldsEntry{Manager: "kustomize-controller", Operation: metav1.ManagedFieldsOperationApply}, false},
+	} {
+		o := &metav1.ObjectMeta{Namespace: tc.namespace}
+		if IsManualManager(o, tc.entry) != tc.expected {
+			t.Errorf("%s %s in %s should be manual: %v", tc.entry.Manager, tc.entry.Operation, tc.namespace, tc.expected)
+		}
+	}
+}
+
+func TestManualIfTime(t *testing.T) {
+	t.Cleanup(func() { SetConfig(mustParseConfig(defaultConfig)) })
+
+	old := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, tc := range []struct {
+		expr     string
+		entry    metav1.ManagedFieldsEntry
+		expected bool
+	}{
+		// fails without time, falling back to rules
+		{`entry.time > timestamp('2024-01-01T00:00:00Z')`, metav1.ManagedFieldsEntry{Manager: "kubectl"}, true},
+		{`entry.time > timestamp('2024-01-01T00:00:00Z')`, metav1.ManagedFieldsEntry{Manager: "kubectl", Time: &old}, false},
+		{`has(entry.time) && entry.time > timestamp('2024-01-01T00:00:00Z')`, metav1.ManagedFieldsEntry{Manager: "kubectl"}, false},
+	} {
+		c := mustParseConfig(defaultConfig)
+		if err := c.SetManualIf(tc.expr); err != nil {
+			t.Fatalf("cannot compile expression: %s", err)
+		}
+		SetConfig(c)
+
+		if IsManualManager(&metav1.ObjectMeta{}, tc.entry) != tc.expected {
+			t.Errorf("%s with time %v should be manual: %v", tc.expr, tc.entry.Time, tc.expected)
+		}
+	}
+}
+
+func TestManualIfInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"entry.manager",
+		"manual &&",
+		"unknown == 1",
+	} {
+		if _, err := compileManualIf(expr); err == nil {
+			t.Errorf("expression should be invalid: %s", expr)
+		}
+	}
+
+	if _, err := ParseConfig([]byte("manualIf: entry.manager")); err == nil {
+		t.Errorf("config with non-bool manualIf should be invalid")
+	}
+}
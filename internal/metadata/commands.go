@@ -0,0 +1,38 @@
+package metadata
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Guesses the command that most likely produced a managed fields entry
+//
+// kubectl sets field manager per subcommand, see k8s.io/kubectl/pkg/cmd,
+// others without one fall back to user-agent "kubectl".
+// Returns empty string if unknown.
+func GuessCommand(e metav1.ManagedFieldsEntry) string {
+	switch e.Manager {
+	case "kubectl-client-side-apply":
+		return "kubectl apply"
+	case "kubectl-last-applied":
+		return "kubectl apply --server-side"
+	case "kubectl":
+		switch {
+		case e.Operation == metav1.ManagedFieldsOperationApply:
+			return "kubectl apply --server-side"
+		case e.Subresource == "scale":
+			return "kubectl scale"
+		case e.Subresource == "ephemeralcontainers":
+			return "kubectl debug"
+		}
+		return "kubectl"
+	case "helm", "Helm":
+		return "helm"
+	}
+
+	if c, ok := strings.CutPrefix(e.Manager, "kubectl-"); ok {
+		return "kubectl " + c
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package metadata
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGuessCommand(t *testing.T) {
+	for _, tc := range []struct {
+		entry    metav1.ManagedFieldsEntry
+		expected string
+	}{
+		{metav1.ManagedFieldsEntry{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate}, "kubectl edit"},
+		{metav1.ManagedFieldsEntry{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate}, "kubectl apply"},
+		{metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}, "kubectl apply --server-side"},
+		{metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "scale"}, "kubectl scale"},
+		{metav1.ManagedFieldsEntry{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate}, "kubectl"},
+		{metav1.ManagedFieldsEntry{Manager: "Helm", Operation: metav1.ManagedFieldsOperationUpdate}, "helm"},
+		{metav1.ManagedFieldsEntry{Manager: "k9s", Operation: metav1.ManagedFieldsOperationUpdate}, ""},
+	} {
+		if c := GuessCommand(tc.entry); c != tc.expected {
+			t.Errorf("command of %s %s should be %q, got %q", tc.entry.Manager, tc.entry.Operation, tc.expected, c)
+		}
+	}
+}
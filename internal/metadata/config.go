@@ -0,0 +1,242 @@
+package metadata
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/cel-go/cel"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+)
+
+var (
+	//go:embed default_config.yaml
+	defaultConfig []byte
+
+	config = mustParseConfig(defaultConfig)
+)
+
+// Matches a field manager by exactly one of name, glob or regex
+//
+// Glob follows path.Match, regex is matched against the whole name.
+type ManagerMatcher struct {
+	Name  string `yaml:"name,omitempty"`
+	Glob  string `yaml:"glob,omitempty"`
+	Regex string `yaml:"regex,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+func (m *ManagerMatcher) compile() error {
+	set := 0
+	for _, s := range []string{m.Name, m.Glob, m.Regex} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of name, glob, regex should be set")
+	}
+
+	if m.Glob != "" {
+		if _, err := path.Match(m.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %s", m.Glob, err)
+		}
+	}
+	if m.Regex != "" {
+		r, err := regexp.Compile("^(?:" + m.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %s", m.Regex, err)
+		}
+		m.regex = r
+	}
+	return nil
+}
+
+func (m ManagerMatcher) Matches(manager string) bool {
+	switch {
+	case m.Name != "":
+		return manager == m.Name
+	case m.Glob != "":
+		ok, _ := path.Match(m.Glob, manager)
+		return ok
+	case m.regex != nil:
+		return m.regex.MatchString(manager)
+	}
+	return false
+}
+
+// Fields considered harmless for matching managers
+//
+// Fields are in managedFields notation, like [f:metadata, f:labels, f:app].
+// A managed fields entry that touches only these fields is not manual.
+type Exemption struct {
+	Manager ManagerMatcher `yaml:"manager"`
+	Fields  [][]string     `yaml:"fields"`
+
+	set *fieldpath.Set
+}
+
+func (e *Exemption) compile() error {
+	if err := e.Manager.compile(); err != nil {
+		return fmt.Errorf("manager: %s", err)
+	}
+
+	e.set = &fieldpath.Set{}
+	for i, f := range e.Fields {
+		p := make(fieldpath.Path, 0, len(f))
+		for _, s := range f {
+			pe, err := fieldpath.DeserializePathElement(s)
+			if err != nil {
+				return fmt.Errorf("fields[%d]: invalid path element %q: %s", i, s, err)
+			}
+			p = append(p, pe)
+		}
+		if len(p) == 0 {
+			return fmt.Errorf("fields[%d]: empty path", i)
+		}
+		e.set.Insert(p)
+	}
+	return nil
+}
+
+// Rules for telling whether a field manager might be operated manually
+//
+// A manager is manual if it matches any of Include, and none of Exclude.
+// If ManualIf is set, the CEL expression makes the final decision instead.
+type Config struct {
+	Include    []ManagerMatcher `yaml:"include"`
+	Exclude    []ManagerMatcher `yaml:"exclude"`
+	Exemptions []Exemption      `yaml:"exemptions"`
+	ManualIf   string           `yaml:"manualIf"`
+
+	manualIf cel.Program
+	// whether evaluation errors of manualIf have been warned of
+	manualIfWarned atomic.Bool
+}
+
+func (c *Config) SetManualIf(expr string) error {
+	p, err := compileManualIf(expr)
+	if err != nil {
+		return err
+	}
+	c.ManualIf = expr
+	c.manualIf = p
+	c.manualIfWarned.Store(false)
+	return nil
+}
+
+func matchesAny(ms []ManagerMatcher, manager string) bool {
+	for _, m := range ms {
+		if m.Matches(manager) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) isManual(manager string) bool {
+	return matchesAny(c.Include, manager) && !matchesAny(c.Exclude, manager)
+}
+
+// Returns union of fields exempted for the manager, nil if none
+func (c *Config) exemptedSet(manager string) *fieldpath.Set {
+	var s *fieldpath.Set
+	for _, e := range c.Exemptions {
+		if !e.Manager.Matches(manager) {
+			continue
+		}
+		if s == nil {
+			s = e.set
+		} else {
+			s = s.Union(e.set)
+		}
+	}
+	return s
+}
+
+func ParseConfig(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalWithOptions(b, c, yaml.Strict()); err != nil {
+		return nil, err
+	}
+
+	for i := range c.Include {
+		if err := c.Include[i].compile(); err != nil {
+			return nil, fmt.Errorf("include[%d]: %s", i, err)
+		}
+	}
+	for i := range c.Exclude {
+		if err := c.Exclude[i].compile(); err != nil {
+			return nil, fmt.Errorf("exclude[%d]: %s", i, err)
+		}
+	}
+	for i := range c.Exemptions {
+		if err := c.Exemptions[i].compile(); err != nil {
+			return nil, fmt.Errorf("exemptions[%d]: %s", i, err)
+		}
+	}
+	if c.ManualIf != "" {
+		if err := c.SetManualIf(c.ManualIf); err != nil {
+			return nil, fmt.Errorf("manualIf: %s", err)
+		}
+	}
+	return c, nil
+}
+
+func mustParseConfig(b []byte) *Config {
+	c, err := ParseConfig(b)
+	if err != nil {
+		panic(fmt.Errorf("cannot parse built-in config: %s", err))
+	}
+	return c
+}
+
+func DefaultConfigPath() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "kubectl-mutated", "config.yaml"), nil
+}
+
+// Loads config from path
+//
+// If path is empty, DefaultConfigPath is tried,
+// and falls back to the built-in config if it does not exist.
+func LoadConfig(p string) (*Config, error) {
+	if p == "" {
+		var err error
+		p, err = DefaultConfigPath()
+		if err != nil {
+			return mustParseConfig(defaultConfig), nil
+		}
+
+		if _, err := os.Stat(p); errors.Is(err, fs.ErrNotExist) {
+			return mustParseConfig(defaultConfig), nil
+		}
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", p, err)
+	}
+	return c, nil
+}
+
+// Sets config used by IsManualManager and related functions
+func SetConfig(c *Config) {
+	config = c
+}
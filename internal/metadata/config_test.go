@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	c := mustParseConfig(defaultConfig)
+
+	for manager, expected := range map[string]bool{
+		"kubectl":                   true,
+		"kubectl-edit":              true,
+		"kubectl-client-side-apply": true,
+		"kubectl-rollout":           false,
+		"helm":                      true,
+		"Helm":                      true,
+		"Sparkles":                  true,
+		"flux":                      true,
+		"helm-controller":           false,
+		"kustomize-controller":      false,
+	} {
+		if c.isManual(manager) != expected {
+			t.Errorf("manager %s should be manual: %v", manager, expected)
+		}
+	}
+}
+
+func TestConfigMatchers(t *testing.T) {
+	c, err := ParseConfig([]byte(`
+include:
+- name: k9s
+- glob: lens-*
+- regex: script-[0-9]+
+exclude:
+- glob: lens-bot*
+`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+
+	for manager, expected := range map[string]bool{
+		"k9s":         true,
+		"k9s-extra":   false,
+		"lens-app":    true,
+		"lens-bot1":   false,
+		"script-42":   true,
+		"script-a":    false,
+		"myscript-42": false,
+		"kubectl":     false,
+	} {
+		if c.isManual(manager) != expected {
+			t.Errorf("manager %s should be manual: %v", manager, expected)
+		}
+	}
+}
+
+func TestConfigInvalid(t *testing.T) {
+	for _, b := range []string{
+		"include: [{name: a, glob: b}]",
+		"include: [{}]",
+		"include: [{glob: '['}]",
+		"exclude: [{regex: '('}]",
+		"includes: []",
+	} {
+		if _, err := ParseConfig([]byte(b)); err == nil {
+			t.Errorf("config should be invalid: %s", b)
+		}
+	}
+}
+
+func TestIsManualManagerUsesConfig(t *testing.T) {
+	t.Cleanup(func() { SetConfig(mustParseConfig(defaultConfig)) })
+
+	c, err := ParseConfig([]byte("include: [{name: k9s}]"))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	SetConfig(c)
+
+	if !IsManualManager(&metav1.ObjectMeta{}, metav1.ManagedFieldsEntry{Manager: "k9s"}) {
+		t.Errorf("k9s should be manual")
+	}
+	if IsManualManager(&metav1.ObjectMeta{}, metav1.ManagedFieldsEntry{Manager: "kubectl-edit"}) {
+		t.Errorf("kubectl-edit should not be manual")
+	}
+}
+
+func TestExemptions(t *testing.T) {
+	t.Cleanup(func() { SetConfig(mustParseConfig(defaultConfig)) })
+
+	c, err := ParseConfig([]byte(`
+include:
+- glob: kubectl*
+exemptions:
+- manager:
+    name: kubectl-annotate
+  fields:
+  - [f:metadata, f:annotations, f:argocd.argoproj.io/refresh]
+`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	SetConfig(c)
+
+	refresh := `{"f:metadata":{"f:annotations":{"f:argocd.argoproj.io/refresh":{}}}}`
+	other := `{"f:metadata":{"f:annotations":{"f:argocd.argoproj.io/refresh":{},"f:foo":{}}}}`
+	for _, tc := range []struct {
+		manager  string
+		fields   string
+		expected bool
+	}{
+		{"kubectl-annotate", refresh, false},
+		{"kubectl-annotate", other, true},
+		{"kubectl-edit", refresh, true},
+	} {
+		e := metav1.ManagedFieldsEntry{
+			Manager:  tc.manager,
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(tc.fields)},
+		}
+		if IsManualManager(&metav1.ObjectMeta{}, e) != tc.expected {
+			t.Errorf("manager %s with %s should be manual: %v", tc.manager, tc.fields, tc.expected)
+		}
+	}
+}
+
+func TestExemptionsInvalid(t *testing.T) {
+	for _, b := range []string{
+		"exemptions: [{manager: {}, fields: [[f:metadata]]}]",
+		"exemptions: [{manager: {name: a}, fields: [[metadata]]}]",
+		"exemptions: [{manager: {name: a}, fields: [[]]}]",
+	} {
+		if _, err := ParseConfig([]byte(b)); err == nil {
+			t.Errorf("config should be invalid: %s", b)
+		}
+	}
+}
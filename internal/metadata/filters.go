@@ -1,20 +1,147 @@
 package metadata
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
 )
 
-func HasManuallyManagedFields(i *resource.Info, err error) (bool, error) {
-	if err != nil {
-		return false, err
+// Returns a filter selecting objects of the GroupKind with manually mutated fields
+func HasManuallyManagedFields(gk schema.GroupKind) resource.FilterFunc {
+	return func(i *resource.Info, err error) (bool, error) {
+		if err != nil {
+			return false, err
+		}
+		o, ok := i.Object.(metav1.Object)
+		if !ok {
+			return false, fmt.Errorf("unexpected type")
+		}
+
+		m, err := FindMutation(o, gk)
+		if err != nil {
+			return false, err
+		}
+		return !m.Empty(), nil
 	}
-	o, ok := i.Object.(metav1.Object)
-	if !ok {
-		return false, fmt.Errorf("unexpected type")
+}
+
+// Selects manual managed fields entries of interest
+type EntryFilter func(e metav1.ManagedFieldsEntry) bool
+
+var (
+	entryFilters []EntryFilter
+)
+
+// Sets filters that manual entries have to pass to be found by FindSoleManualManagers
+func SetEntryFilters(fs ...EntryFilter) {
+	entryFilters = fs
+}
+
+func matchesEntryFilters(e metav1.ManagedFieldsEntry) bool {
+	for _, f := range entryFilters {
+		if !f(e) {
+			return false
+		}
 	}
+	return true
+}
 
-	return len(FindSoleManualManagers(o.GetManagedFields())) > 0, nil
+func OperationFilter(op metav1.ManagedFieldsOperationType) EntryFilter {
+	return func(e metav1.ManagedFieldsEntry) bool {
+		return e.Operation == op
+	}
+}
+
+// Selects entries by subresource, "" for the main resource
+//
+// If include is not empty, only included subresources are selected.
+func SubresourceFilter(include, exclude []string) EntryFilter {
+	return func(e metav1.ManagedFieldsEntry) bool {
+		if len(include) != 0 && !slices.Contains(include, e.Subresource) {
+			return false
+		}
+		return !slices.Contains(exclude, e.Subresource)
+	}
+}
+
+// Current time, replaced in tests
+var now = time.Now
+
+// Selects entries last updated within since, and longer than olderThan ago
+//
+// Zero duration means unbounded. Bounds are relative to when entries are
+// evaluated, not when the filter is made, for rescans of serve.
+// Entries without time are not selected.
+func TimeFilter(since, olderThan time.Duration) EntryFilter {
+	return func(e metav1.ManagedFieldsEntry) bool {
+		if e.Time == nil {
+			return false
+		}
+		t := now()
+		if since != 0 && !e.Time.Time.After(t.Add(-since)) {
+			return false
+		}
+		return olderThan == 0 || e.Time.Time.Before(t.Add(-olderThan))
+	}
+}
+
+var (
+	includedFields, ignoredFields []*fieldpaths.Pattern
+)
+
+// Sets patterns of fields to consider, and to ignore, of manual entries
+//
+// If include is empty, all fields not ignored are considered.
+// Entries without any field considered are not found by FindSoleManualManagers.
+func SetFieldFilters(include, ignore []*fieldpaths.Pattern) {
+	includedFields, ignoredFields = include, ignore
+}
+
+func isFieldSelected(p fieldpath.Path) bool {
+	matches := func(pt *fieldpaths.Pattern) bool {
+		return pt.Matches(p)
+	}
+	if len(includedFields) != 0 && !slices.ContainsFunc(includedFields, matches) {
+		return false
+	}
+	return !slices.ContainsFunc(ignoredFields, matches)
+}
+
+// Narrows fields of an entry to ones selected by SetFieldFilters,
+// returns false if none is
+func selectFields(e metav1.ManagedFieldsEntry) (metav1.ManagedFieldsEntry, bool) {
+	if len(includedFields) == 0 && len(ignoredFields) == 0 {
+		return e, true
+	}
+
+	s := fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw)); err != nil {
+		klog.Warning("found invalid FieldsV1", "manager", e.Manager, "fieldsV1", string(e.FieldsV1.Raw))
+		return e, true
+	}
+
+	selected := &fieldpath.Set{}
+	s.Leaves().Iterate(func(p fieldpath.Path) {
+		if isFieldSelected(p) {
+			selected.Insert(p)
+		}
+	})
+	if selected.Empty() {
+		return e, false
+	}
+
+	e, err := withSet(e, selected)
+	if err != nil {
+		return e, false
+	}
+	return e, true
 }
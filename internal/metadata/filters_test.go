@@ -0,0 +1,147 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+)
+
+func TestEntryFilters(t *testing.T) {
+	t.Cleanup(func() { SetEntryFilters() })
+
+	now := time.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	weekAgo := metav1.NewTime(now.Add(-7 * 24 * time.Hour))
+	fields := &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}
+	edit := metav1.ManagedFieldsEntry{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		Time:      &hourAgo,
+		FieldsV1:  fields,
+	}
+	scale := metav1.ManagedFieldsEntry{
+		Manager:     "kubectl",
+		Operation:   metav1.ManagedFieldsOperationUpdate,
+		Subresource: "scale",
+		Time:        &weekAgo,
+		FieldsV1:    fields,
+	}
+	apply := metav1.ManagedFieldsEntry{
+		Manager:   "kubectl",
+		Operation: metav1.ManagedFieldsOperationApply,
+		FieldsV1:  fields,
+	}
+	es := []metav1.ManagedFieldsEntry{edit, scale, apply}
+
+	for name, tc := range map[string]struct {
+		filters  []EntryFilter
+		expected []string
+	}{
+		"none": {
+			nil,
+			[]string{"kubectl-edit", "kubectl", "kubectl"},
+		},
+		"operation": {
+			[]EntryFilter{OperationFilter(metav1.ManagedFieldsOperationApply)},
+			[]string{"kubectl"},
+		},
+		"subresource": {
+			[]EntryFilter{SubresourceFilter([]string{"scale"}, nil)},
+			[]string{"kubectl"},
+		},
+		"exclude subresource": {
+			[]EntryFilter{SubresourceFilter(nil, []string{"scale"})},
+			[]string{"kubectl-edit", "kubectl"},
+		},
+		"since": {
+			[]EntryFilter{TimeFilter(2*time.Hour, 0)},
+			[]string{"kubectl-edit"},
+		},
+		"older than": {
+			[]EntryFilter{TimeFilter(0, 2*time.Hour)},
+			[]string{"kubectl"},
+		},
+	} {
+		SetEntryFilters(tc.filters...)
+		found := FindSoleManualManagers(&metav1.ObjectMeta{ManagedFields: es})
+		if len(found) != len(tc.expected) {
+			t.Fatalf("%s: should find %d entries, got %d", name, len(tc.expected), len(found))
+		}
+		for i, e := range found {
+			if e.Manager != tc.expected[i] {
+				t.Errorf("%s: entry %d should be of %s, got %s", name, i, tc.expected[i], e.Manager)
+			}
+		}
+	}
+}
+
+func TestTimeFilterRescan(t *testing.T) {
+	t.Cleanup(func() { now = time.Now })
+
+	start := time.Now()
+	now = func() time.Time { return start }
+	updated := metav1.NewTime(start.Add(-time.Hour))
+	e := metav1.ManagedFieldsEntry{Manager: "kubectl-edit", Time: &updated}
+
+	f := TimeFilter(2*time.Hour, 0)
+	if !f(e) {
+		t.Errorf("entry updated an hour ago should be selected with --since 2h")
+	}
+	// like the next scan of serve, with the same filter
+	now = func() time.Time { return start.Add(2 * time.Hour) }
+	if f(e) {
+		t.Errorf("entry updated three hours ago should not be selected with --since 2h")
+	}
+}
+
+func TestFieldFilters(t *testing.T) {
+	t.Cleanup(func() { SetFieldFilters(nil, nil) })
+
+	o := &metav1.ObjectMeta{
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "kubectl-label",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:foo":{}}}}`)},
+			},
+			{
+				Manager:   "kubectl-edit",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:foo":{}}},"f:spec":{"f:replicas":{}}}`)},
+			},
+		},
+	}
+
+	compile := func(ps ...string) []*fieldpaths.Pattern {
+		res := []*fieldpaths.Pattern{}
+		for _, p := range ps {
+			c, err := fieldpaths.CompilePattern(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, c)
+		}
+		return res
+	}
+
+	SetFieldFilters(compile("spec.replicas"), nil)
+	found := FindSoleManualManagers(o)
+	if len(found) != 1 || found[0].Manager != "kubectl-edit" {
+		t.Fatalf("should only find entry with selected fields, got %v", found)
+	}
+	s, err := SolelyManuallyManagedSet(o)
+	if err != nil {
+		t.Fatalf("cannot conclude field set: %s", err)
+	}
+	assertSetHasPath(t, s, "spec", "replicas")
+	assertSetNotHasPath(t, s, "metadata", "annotations", "foo")
+
+	SetFieldFilters(nil, compile("metadata.*"))
+	found = FindSoleManualManagers(o)
+	if len(found) != 1 || found[0].Manager != "kubectl-edit" {
+		t.Fatalf("should not find entry with fields all ignored, got %v", found)
+	}
+}
@@ -2,34 +2,51 @@ package metadata
 
 import (
 	"bytes"
-	"strings"
+	"slices"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
 )
 
 var (
-	fluxReconcileSet = fieldpath.NewSet(
-		fieldpath.MakePathOrDie(
-			"metadata",
-			"annotations",
-			"reconcile.fluxcd.io/requestedAt",
-		),
-		fieldpath.MakePathOrDie(
-			"metadata",
-			"annotations",
-			"reconcile.fluxcd.io/forceAt",
-		),
-	)
+	// managers of helm cli, on released objects and storage
+	helmManagers = []string{"helm", "Helm"}
 )
 
 // Returns if something useful is managed by a manual manager
 //
-// Manager is either explicitly specified, or from user-agent before '/'
-// see k8s.io/apiserver/pkg/endpoints/handlers.managerOrUserAgent
-func IsManualManager(e metav1.ManagedFieldsEntry) bool {
-	if e.Manager == "flux" {
+// Whether a manager is manual is decided by rules set via SetConfig,
+// entries touching only exempted fields of the manager are not manual.
+// If the config has a manualIf expression, it makes the final decision.
+func IsManualManager(o metav1.Object, e metav1.ManagedFieldsEntry) bool {
+	manual := isManualByRules(e)
+	if config.manualIf == nil {
+		return manual
+	}
+
+	b, err := evalManualIf(config.manualIf, o, e, manual)
+	if err != nil {
+		// likely the same for every entry, like entry.time without has(entry.time)
+		if config.manualIfWarned.CompareAndSwap(false, true) {
+			klog.Warningf("cannot evaluate manualIf for manager %s, falling back to rules, "+
+				"further errors logged with -v=1: %s", e.Manager, err)
+		} else {
+			klog.V(1).Infof("cannot evaluate manualIf for manager %s, falling back to rules: %s", e.Manager, err)
+		}
+		return manual
+	}
+	return b
+}
+
+func isManualByRules(e metav1.ManagedFieldsEntry) bool {
+	if !config.isManual(e.Manager) {
+		return false
+	}
+
+	if exempted := config.exemptedSet(e.Manager); exempted != nil {
 		s := fieldpath.Set{}
 		err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw))
 		if err != nil {
@@ -37,43 +54,94 @@ func IsManualManager(e metav1.ManagedFieldsEntry) bool {
 			return true
 		}
 
-		// flux reconcile
-		if s.Leaves().Difference(fluxReconcileSet).Empty() {
+		if s.Leaves().Difference(exempted).Empty() {
 			return false
 		}
-		return true
 	}
+	return true
+}
 
-	return (strings.HasPrefix(e.Manager, "kubectl") && e.Manager != "kubectl-rollout") ||
-		// helm cli managed resources (via its generic client)
-		// (flux helm-controller uses "helm-controller")
-		e.Manager == "helm" ||
-		// helm cli storage (secrets, configmaps) implicitly via user-agent Helm/<version>
-		// (flux helm-controller uses "helm-controller")
-		e.Manager == "Helm" ||
-		e.Manager == "Sparkles"
+func withSet(e metav1.ManagedFieldsEntry, s *fieldpath.Set) (metav1.ManagedFieldsEntry, error) {
+	b, err := s.ToJSON()
+	if err != nil {
+		return e, err
+	}
+	e.FieldsType = "FieldsV1"
+	e.FieldsV1 = &metav1.FieldsV1{Raw: b}
+	return e, nil
 }
 
-// TODO perhaps a cached variant by metadata.uid?
-func FindSoleManualManagers(es []metav1.ManagedFieldsEntry) []metav1.ManagedFieldsEntry {
-	candidates := []metav1.ManagedFieldsEntry{}
+// Splits an entry of helm by whether fields are set by the released manifest
+//
+// Fields set by the release are machine managed, the rest are hand-edited.
+func splitReleased(e metav1.ManagedFieldsEntry, released map[string]any) (chart, edited *metav1.ManagedFieldsEntry) {
+	s := fieldpath.Set{}
+	if err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw)); err != nil {
+		klog.Warning("found invalid FieldsV1", "manager", e.Manager, "fieldsV1", string(e.FieldsV1.Raw))
+		return nil, &e
+	}
 
-	systemManagedSet := &fieldpath.Set{}
-	for _, e := range es {
-		if IsManualManager(e) {
-			candidates = append(candidates, e)
-		} else {
-			s := fieldpath.Set{}
-			err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw))
-			if err != nil {
-				klog.Warning("found invalid FieldsV1", "manager", e.Manager, "fieldsV1", string(e.FieldsV1.Raw))
+	present, absent := fieldpaths.Partition(s.Leaves(), released)
+	if !present.Empty() {
+		if c, err := withSet(e, present); err == nil {
+			chart = &c
+		}
+	}
+	if !absent.Empty() {
+		if a, err := withSet(e, absent); err == nil {
+			edited = &a
+		}
+	}
+	return chart, edited
+}
+
+// Splits entries into manual ones of interest, and ones of machine managers
+//
+// Manual entries not of interest, per SetEntryFilters, are in neither.
+// Fields of manual entries are narrowed per SetFieldFilters.
+// If the object is from a Helm release, fields of helm entries set by the
+// release are considered machine managed.
+func classify(o metav1.Object, released map[string]any) (manual, machine []metav1.ManagedFieldsEntry) {
+	for _, e := range o.GetManagedFields() {
+		if !IsManualManager(o, e) {
+			machine = append(machine, e)
+			continue
+		}
+
+		if released != nil && slices.Contains(helmManagers, e.Manager) {
+			chart, edited := splitReleased(e, released)
+			if chart != nil {
+				machine = append(machine, *chart)
+			}
+			if edited == nil {
 				continue
 			}
+			e = *edited
+		}
+		e, ok := selectFields(e)
+		if ok && matchesEntryFilters(e) {
+			manual = append(manual, e)
+		}
+	}
+	return manual, machine
+}
 
-			systemManagedSet = systemManagedSet.Union(s.Leaves()).Leaves()
+func systemManagedSet(machine []metav1.ManagedFieldsEntry) *fieldpath.Set {
+	systemManagedSet := &fieldpath.Set{}
+	for _, e := range machine {
+		s := fieldpath.Set{}
+		err := s.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw))
+		if err != nil {
+			klog.Warning("found invalid FieldsV1", "manager", e.Manager, "fieldsV1", string(e.FieldsV1.Raw))
+			continue
 		}
+
+		systemManagedSet = systemManagedSet.Union(s.Leaves()).Leaves()
 	}
+	return systemManagedSet
+}
 
+func soleAmong(candidates []metav1.ManagedFieldsEntry, systemManagedSet *fieldpath.Set) []metav1.ManagedFieldsEntry {
 	res := []metav1.ManagedFieldsEntry{}
 	for _, e := range candidates {
 		s := fieldpath.Set{}
@@ -91,9 +159,17 @@ func FindSoleManualManagers(es []metav1.ManagedFieldsEntry) []metav1.ManagedFiel
 	return res
 }
 
-func SolelyManuallyManagedSet(mfs []metav1.ManagedFieldsEntry) (*fieldpath.Set, error) {
+// TODO perhaps a cached variant by metadata.uid?
+//
+// Desired state is not considered, see FindMutation for that.
+func FindSoleManualManagers(o metav1.Object) []metav1.ManagedFieldsEntry {
+	candidates, machine := classify(o, nil)
+	return soleAmong(candidates, systemManagedSet(machine))
+}
+
+func entriesSet(es []metav1.ManagedFieldsEntry) (*fieldpath.Set, error) {
 	s := &fieldpath.Set{}
-	for _, mf := range FindSoleManualManagers(mfs) {
+	for _, mf := range es {
 		ms := &fieldpath.Set{}
 		err := ms.FromJSON(bytes.NewBuffer(mf.FieldsV1.Raw))
 		if err != nil {
@@ -103,3 +179,7 @@ func SolelyManuallyManagedSet(mfs []metav1.ManagedFieldsEntry) (*fieldpath.Set,
 	}
 	return s.Leaves(), nil
 }
+
+func SolelyManuallyManagedSet(o metav1.Object) (*fieldpath.Set, error) {
+	return entriesSet(FindSoleManualManagers(o))
+}
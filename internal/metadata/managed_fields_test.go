@@ -70,7 +70,7 @@ func TestSoleyManuallyManagedSetFullyManual(t *testing.T) {
 		t.Fatalf("cannot create pod: %s", err)
 	}
 
-	set, err := SolelyManuallyManagedSet(created.ManagedFields)
+	set, err := SolelyManuallyManagedSet(created)
 	if err != nil {
 		t.Fatalf("cannot find solely manually managed set: %s", set)
 	}
@@ -100,7 +100,7 @@ func TestSoleyManuallyManagedSetFullyMachine(t *testing.T) {
 		t.Fatalf("cannot create pod: %s", err)
 	}
 
-	set, err := SolelyManuallyManagedSet(created.ManagedFields)
+	set, err := SolelyManuallyManagedSet(created)
 	if err != nil {
 		t.Fatalf("cannot find solely manually managed set: %s", set)
 	}
@@ -152,7 +152,7 @@ func TestSoleyManuallyManagedSetCoManaged(t *testing.T) {
 		t.Fatalf("cannot apply pod: %s", err)
 	}
 
-	set, err := SolelyManuallyManagedSet(applied.ManagedFields)
+	set, err := SolelyManuallyManagedSet(applied)
 	if err != nil {
 		t.Fatalf("cannot find solely manually managed set: %s", set)
 	}
@@ -205,7 +205,7 @@ func TestSoleyManuallyManagedSetMixed(t *testing.T) {
 		t.Fatalf("cannot apply pod: %s", err)
 	}
 
-	set, err := SolelyManuallyManagedSet(applied.ManagedFields)
+	set, err := SolelyManuallyManagedSet(applied)
 	if err != nil {
 		t.Fatalf("cannot find solely manually managed set: %s", set)
 	}
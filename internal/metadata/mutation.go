@@ -0,0 +1,167 @@
+package metadata
+
+import (
+	"bytes"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/desired"
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+)
+
+var (
+	includeShared = false
+	desiredState  *desired.State
+)
+
+// Sets whether to report fields co-owned by manual and machine managers
+//
+// Such fields are reported as Mutation.Shared instead of Mutation.Set.
+func SetIncludeShared(b bool) {
+	includeShared = b
+}
+
+// Sets desired state to compare mutated fields against, nil to disable
+//
+// Fields present in desired state are reported as Mutation.Desired.
+func SetDesiredState(s *desired.State) {
+	desiredState = s
+}
+
+// Fields of an object mutated by manual managers
+type Mutation struct {
+	// Manual managed fields entries
+	Entries []metav1.ManagedFieldsEntry
+	// Fields managed by manual managers, see SolelyManuallyManagedSet
+	Set *fieldpath.Set
+	// Fields co-owned with machine managers, nil unless SetIncludeShared
+	Shared *fieldpath.Set
+	// Machine managed fields entries co-owning Shared
+	CoOwners []metav1.ManagedFieldsEntry
+	// Fields of Set present in desired state, thus overwritten on next sync,
+	// nil unless SetDesiredState
+	Desired *fieldpath.Set
+
+	system  *fieldpath.Set
+	machine []metav1.ManagedFieldsEntry
+	desired map[string]any
+}
+
+// Returns the object from Helm releases in desired state, nil if not found
+func releasedObject(o metav1.Object, gk schema.GroupKind) map[string]any {
+	if desiredState == nil {
+		return nil
+	}
+	if u := desiredState.GetReleased(gk, o.GetNamespace(), o.GetName()); u != nil {
+		return u.Object
+	}
+	return nil
+}
+
+// Finds fields of an object mutated by manual managers
+//
+// With desired state of Helm releases, fields set by a release are not
+// considered mutated by helm.
+func FindMutation(o metav1.Object, gk schema.GroupKind) (*Mutation, error) {
+	var d map[string]any
+	if desiredState != nil {
+		d = map[string]any{}
+		if u := desiredState.Get(gk, o.GetNamespace(), o.GetName()); u != nil {
+			d = u.Object
+		}
+	}
+
+	manual, machine := classify(o, releasedObject(o, gk))
+	system := systemManagedSet(machine)
+	if !includeShared {
+		manual = soleAmong(manual, system)
+	}
+	return newMutation(manual, system, machine, d)
+}
+
+func newMutation(
+	es []metav1.ManagedFieldsEntry,
+	system *fieldpath.Set,
+	machine []metav1.ManagedFieldsEntry,
+	d map[string]any,
+) (*Mutation, error) {
+	s, err := entriesSet(es)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mutation{Entries: es, Set: s, system: system, machine: machine, desired: d}
+	if includeShared {
+		m.Shared = s.Intersection(system)
+		m.Set = s.Difference(system)
+		m.CoOwners = []metav1.ManagedFieldsEntry{}
+		for _, e := range machine {
+			ms := &fieldpath.Set{}
+			if err := ms.FromJSON(bytes.NewBuffer(e.FieldsV1.Raw)); err != nil {
+				continue
+			}
+			if !ms.Leaves().Intersection(m.Shared).Empty() {
+				m.CoOwners = append(m.CoOwners, e)
+			}
+		}
+	}
+	if d != nil {
+		m.Desired, _ = fieldpaths.Partition(m.Set, d)
+	}
+	return m, nil
+}
+
+// Fields of Set absent from desired state, thus sticking around
+func (m *Mutation) Sticky() *fieldpath.Set {
+	if m.Desired == nil {
+		return m.Set
+	}
+	return m.Set.Difference(m.Desired)
+}
+
+func (m *Mutation) Empty() bool {
+	return m.Set.Empty() && (m.Shared == nil || m.Shared.Empty())
+}
+
+// Breaks out the mutation by keys of entries
+func (m *Mutation) GroupBy(key func(metav1.ManagedFieldsEntry) string) (map[string]*Mutation, error) {
+	groups := map[string][]metav1.ManagedFieldsEntry{}
+	for _, e := range m.Entries {
+		k := key(e)
+		groups[k] = append(groups[k], e)
+	}
+
+	res := map[string]*Mutation{}
+	for k, es := range groups {
+		gm, err := newMutation(es, m.system, m.machine, m.desired)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = gm
+	}
+	return res, nil
+}
+
+// Breaks out the mutation per subresource
+//
+// Keyed by subresource, or empty string for the main resource.
+func (m *Mutation) BySubresource() (map[string]*Mutation, error) {
+	return m.GroupBy(func(e metav1.ManagedFieldsEntry) string {
+		return e.Subresource
+	})
+}
+
+// Names of machine managers co-owning Shared, sorted
+func (m *Mutation) CoOwnerNames() []string {
+	names := []string{}
+	for _, e := range m.CoOwners {
+		if !slices.Contains(names, e.Manager) {
+			names = append(names, e.Manager)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
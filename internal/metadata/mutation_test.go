@@ -0,0 +1,213 @@
+package metadata
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/desired"
+)
+
+var (
+	podGK = schema.GroupKind{Kind: "Pod"}
+)
+
+func TestFindMutationShared(t *testing.T) {
+	t.Cleanup(func() { SetIncludeShared(false) })
+
+	o := &metav1.ObjectMeta{
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   machineFieldManager,
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)},
+			},
+			{
+				Manager:   manualFieldManager,
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)},
+			},
+			{
+				Manager:   "kubectl-label",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:foo":{}}}}`)},
+			},
+		},
+	}
+
+	m, err := FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if len(m.Entries) != 1 || m.Shared != nil {
+		t.Fatalf("should only find the sole manual entry without shared mode")
+	}
+
+	SetIncludeShared(true)
+	m, err = FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if len(m.Entries) != 2 {
+		t.Fatalf("should find both manual entries in shared mode, got %d", len(m.Entries))
+	}
+	assertSetHasPath(t, m.Set, "metadata", "labels", "foo")
+	assertSetNotHasPath(t, m.Set, "metadata", "labels", "app")
+	assertSetHasPath(t, m.Shared, "metadata", "labels", "app")
+	if !slices.Equal(m.CoOwnerNames(), []string{machineFieldManager}) {
+		t.Errorf("co-owners should be %s, got %v", machineFieldManager, m.CoOwnerNames())
+	}
+}
+
+func TestFindMutationDesired(t *testing.T) {
+	t.Cleanup(func() { SetDesiredState(nil) })
+
+	d := desired.NewState()
+	err := d.Add(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":   "test",
+			"labels": map[string]any{"app": "test"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("cannot add desired object: %s", err)
+	}
+	SetDesiredState(d)
+
+	o := &metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "default",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "kubectl-label",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{},"f:foo":{}}}}`)},
+			},
+		},
+	}
+
+	m, err := FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	assertSetHasPath(t, m.Desired, "metadata", "labels", "app")
+	assertSetNotHasPath(t, m.Desired, "metadata", "labels", "foo")
+	assertSetHasPath(t, m.Sticky(), "metadata", "labels", "foo")
+	assertSetNotHasPath(t, m.Sticky(), "metadata", "labels", "app")
+
+	m, err = FindMutation(o, schema.GroupKind{Kind: "ConfigMap"})
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if !m.Desired.Empty() {
+		t.Errorf("fields should all stick without desired object")
+	}
+}
+
+func TestFindMutationHelmRelease(t *testing.T) {
+	t.Cleanup(func() { SetDesiredState(nil) })
+
+	d := desired.NewState()
+	d.AddReleased(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":      "test",
+			"namespace": "default",
+			"labels":    map[string]any{"app": "test"},
+		},
+	}})
+	SetDesiredState(d)
+
+	o := &metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "default",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "helm",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{},"f:foo":{}}}}`)},
+			},
+		},
+	}
+
+	m, err := FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if len(m.Entries) != 1 {
+		t.Fatalf("should find helm entry with fields edited after release")
+	}
+	assertSetHasPath(t, m.Set, "metadata", "labels", "foo")
+	assertSetNotHasPath(t, m.Set, "metadata", "labels", "app")
+
+	m, err = FindMutation(o, schema.GroupKind{Kind: "ConfigMap"})
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	assertSetHasPath(t, m.Set, "metadata", "labels", "app")
+
+	o.ManagedFields[0].FieldsV1.Raw = []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)
+	m, err = FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if !m.Empty() {
+		t.Errorf("fields set by release should not be mutated")
+	}
+}
+
+func TestFindMutationHelmMetadata(t *testing.T) {
+	t.Cleanup(func() { SetDesiredState(nil) })
+
+	// release stored as by helm, without gzip
+	release := `{"name":"app","namespace":"default","version":1,"info":{"status":"deployed"},` +
+		`"manifest":"apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\n  labels:\n    app: test\n"}`
+	client := fake.NewClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1.app.v1",
+			Namespace: "default",
+			Labels:    map[string]string{"owner": "helm", "name": "app", "status": "deployed"},
+		},
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString([]byte(release)))},
+	})
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGK.WithVersion("v1"), meta.RESTScopeNamespace)
+
+	d := desired.NewState()
+	if err := d.LoadHelmReleases(t.Context(), client, "", mapper); err != nil {
+		t.Fatalf("cannot load helm releases: %s", err)
+	}
+	SetDesiredState(d)
+
+	o := &metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "default",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "helm",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{` +
+					`"f:annotations":{".":{},"f:meta.helm.sh/release-name":{},"f:meta.helm.sh/release-namespace":{}},` +
+					`"f:labels":{".":{},"f:app":{},"f:app.kubernetes.io/managed-by":{}}}}`)},
+			},
+		},
+	}
+
+	m, err := FindMutation(o, podGK)
+	if err != nil {
+		t.Fatalf("cannot find mutation: %s", err)
+	}
+	if !m.Empty() {
+		t.Errorf("metadata set by helm should not be mutated, got %s", m.Set)
+	}
+}
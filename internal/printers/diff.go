@@ -0,0 +1,99 @@
+package printers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/goccy/go-yaml"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+// Prints unified diffs from live objects to ones with mutated fields removed,
+// without colors
+type DiffPrinter struct {
+	unstructuredPrinter
+}
+
+func NewDiffPrinter(withNamespace bool) (*DiffPrinter, error) {
+	return &DiffPrinter{
+		unstructuredPrinter: unstructuredPrinter{
+			withNamespace: withNamespace,
+		},
+	}, nil
+}
+
+func (p *DiffPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, err := p.toUnstructured(r, gvk)
+	if err != nil {
+		return fmt.Errorf("cannot convert to unstructured: %s", err)
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	// fields present in desired state will be overwritten anyway
+	ops, err := fieldpaths.RemovalPatch(m.Sticky(), o.Object)
+	if err != nil {
+		return fmt.Errorf("cannot compute patch: %s", err)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	c := p.forDisplay(o)
+	c.SetManagedFields(nil)
+	live, err := json.Marshal(c.Object)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return fmt.Errorf("cannot decode patch: %s", err)
+	}
+	reverted, err := decoded.Apply(live)
+	if err != nil {
+		return fmt.Errorf("cannot apply patch: %s", err)
+	}
+
+	from, err := yaml.JSONToYAML(live)
+	if err != nil {
+		return fmt.Errorf("cannot marshal YAML: %s", err)
+	}
+	to, err := yaml.JSONToYAML(reverted)
+	if err != nil {
+		return fmt.Errorf("cannot marshal YAML: %s", err)
+	}
+
+	ref := formatNameColumn(o, gvk)
+	if ns := c.GetNamespace(); ns != "" {
+		ref = ns + "/" + ref
+	}
+	d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(string(from), "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(string(to), "\n")),
+		FromFile: "live/" + ref,
+		ToFile:   "reverted/" + ref,
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot diff: %s", err)
+	}
+	fmt.Print(d)
+	return nil
+}
+
+func (p *DiffPrinter) Flush() error {
+	return nil
+}
@@ -0,0 +1,143 @@
+package printers
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+// Findings grouped by namespace and kind, for human-readable reports
+type document struct {
+	Managers   []managerCount
+	Namespaces []*documentNamespace
+}
+
+type documentNamespace struct {
+	// Empty for cluster-scoped objects
+	Name  string
+	Kinds []*documentKind
+}
+
+type documentKind struct {
+	Name    string
+	Objects []documentObject
+}
+
+type documentObject struct {
+	Name     string
+	Managers []managerCount
+	// Rendered filtered object, by the printer
+	YAML any
+}
+
+type managerCount struct {
+	Manager string
+	Objects int
+	Fields  int
+}
+
+// Collects findings into a document, see MarkdownPrinter and HTMLPrinter
+type documentPrinter struct {
+	filteredPrinter
+	namespaces map[string]map[schema.GroupKind][]documentObject
+	managers   map[string]*managerCount
+}
+
+func newDocumentPrinter() documentPrinter {
+	return documentPrinter{
+		filteredPrinter: filteredPrinter{
+			unstructuredPrinter: unstructuredPrinter{
+				// grouped by namespace, but kept for self-contained YAML
+				withNamespace: true,
+			},
+		},
+		namespaces: map[string]map[schema.GroupKind][]documentObject{},
+		managers:   map[string]*managerCount{},
+	}
+}
+
+// Adds an object, with the filtered object rendered by render
+func (p *documentPrinter) add(
+	r runtime.Object,
+	gvk schema.GroupVersionKind,
+	render func(o map[string]any, m *metadata.Mutation) (any, error),
+) error {
+	a, err := meta.Accessor(r)
+	if err != nil {
+		return fmt.Errorf("unexpected type: %s", err)
+	}
+	ns, name := a.GetNamespace(), a.GetName()
+
+	f, m, err := p.getFilteredObject(r, gvk)
+	if err != nil {
+		return fmt.Errorf("cannot get filtered object: %s", err)
+	}
+	y, err := render(f.Object, m)
+	if err != nil {
+		return fmt.Errorf("cannot render object: %s", err)
+	}
+
+	byManager, err := m.GroupBy(func(e metav1.ManagedFieldsEntry) string {
+		return e.Manager
+	})
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	o := documentObject{Name: name, YAML: y}
+	for _, mgr := range slices.Sorted(maps.Keys(byManager)) {
+		mm := byManager[mgr]
+		s := mm.Set
+		if mm.Shared != nil {
+			s = s.Union(mm.Shared)
+		}
+		n := s.Leaves().Size()
+		o.Managers = append(o.Managers, managerCount{Manager: mgr, Objects: 1, Fields: n})
+
+		c, ok := p.managers[mgr]
+		if !ok {
+			c = &managerCount{Manager: mgr}
+			p.managers[mgr] = c
+		}
+		c.Objects++
+		c.Fields += n
+	}
+
+	kinds, ok := p.namespaces[ns]
+	if !ok {
+		kinds = map[schema.GroupKind][]documentObject{}
+		p.namespaces[ns] = kinds
+	}
+	kinds[gvk.GroupKind()] = append(kinds[gvk.GroupKind()], o)
+	return nil
+}
+
+// Sorted document of findings added
+func (p *documentPrinter) document() document {
+	d := document{}
+	for _, mgr := range slices.Sorted(maps.Keys(p.managers)) {
+		d.Managers = append(d.Managers, *p.managers[mgr])
+	}
+
+	for _, ns := range slices.Sorted(maps.Keys(p.namespaces)) {
+		dn := &documentNamespace{Name: ns}
+		kinds := p.namespaces[ns]
+		for _, gk := range slices.SortedFunc(maps.Keys(kinds), func(a, b schema.GroupKind) int {
+			return cmp.Compare(a.String(), b.String())
+		}) {
+			objs := slices.SortedFunc(slices.Values(kinds[gk]), func(a, b documentObject) int {
+				return cmp.Compare(a.Name, b.Name)
+			})
+			dn.Kinds = append(dn.Kinds, &documentKind{Name: gk.String(), Objects: objs})
+		}
+		d.Namespaces = append(d.Namespaces, dn)
+	}
+	return d
+}
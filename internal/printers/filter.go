@@ -2,13 +2,16 @@ package printers
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/goccy/go-yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
-	"sigs.k8s.io/structured-merge-diff/v6/value"
 
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
 	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
 )
 
@@ -46,55 +49,12 @@ func filterMap(v map[string]any, s *fieldpath.Set) (map[string]any, error) {
 	return res, nil
 }
 
-func findIndex(v []any, p fieldpath.PathElement) (int, error) {
-	i := -1
-	switch {
-	case p.Key != nil:
-	MapLoop:
-		for ii, vv := range v {
-			vm, ok := vv.(map[string]any)
-			if !ok {
-				return 0, fmt.Errorf("unexpected type %T", vv)
-			}
-
-			for _, f := range *p.Key {
-				c, ok := vm[f.Name]
-				if !ok {
-					continue MapLoop
-				}
-				if !value.Equals(value.NewValueInterface(c), f.Value) {
-					continue MapLoop
-				}
-			}
-			i = ii
-			break
-		}
-	case p.Value != nil:
-		for ii, vv := range v {
-			if value.Equals(value.NewValueInterface(vv), *p.Value) {
-				i = ii
-				break
-			}
-		}
-	case p.Index != nil:
-		if *p.Index < len(v) {
-			i = *p.Index
-		}
-	default:
-		return 0, fmt.Errorf("path of unexpected type: %s", p)
-	}
-	if i == -1 {
-		return 0, fmt.Errorf("no match for path: %s", p)
-	}
-	return i, nil
-}
-
 func filterSlice(v []any, s *fieldpath.Set) ([]any, error) {
 	used := make([]bool, len(v))
 	vals := make([]any, len(v))
 
 	for p := range s.Members.All() {
-		i, err := findIndex(v, p)
+		i, err := fieldpaths.FindIndex(v, p)
 		if err != nil {
 			return nil, err
 		}
@@ -104,7 +64,7 @@ func filterSlice(v []any, s *fieldpath.Set) ([]any, error) {
 	}
 
 	for p := range s.Children.All() {
-		i, err := findIndex(v, p)
+		i, err := fieldpaths.FindIndex(v, p)
 		if err != nil {
 			return nil, err
 		}
@@ -114,6 +74,12 @@ func filterSlice(v []any, s *fieldpath.Set) ([]any, error) {
 		if err != nil {
 			return nil, err
 		}
+		// keep keys to identify items, like for strategic merge
+		if m, ok := vals[i].(map[string]any); ok && p.Key != nil {
+			for _, f := range *p.Key {
+				m[f.Name] = v[i].(map[string]any)[f.Name]
+			}
+		}
 	}
 
 	res := []any{}
@@ -155,28 +121,125 @@ func Filter(u *unstructured.Unstructured, s *fieldpath.Set) (*unstructured.Unstr
 	return &r, nil
 }
 
+// Manual managed fields entries, followed by co-owners,
+// with fields omitted for brevity
+func displayedEntries(m *metadata.Mutation) []metav1.ManagedFieldsEntry {
+	es := slices.Concat(m.Entries, m.CoOwners)
+	for i := range es {
+		es[i].FieldsType = ""
+		es[i].FieldsV1 = nil
+	}
+	return es
+}
+
+// Comments displayedEntries with the command that likely produced them,
+// or as co-owners
+func entryComments(m *metadata.Mutation) yaml.CommentMap {
+	cm := yaml.CommentMap{}
+	for i, e := range m.Entries {
+		if c := metadata.GuessCommand(e); c != "" {
+			cm[fmt.Sprintf("$.metadata.managedFields[%d].manager", i)] = []*yaml.Comment{
+				yaml.LineComment(" " + c),
+			}
+		}
+	}
+	for i := range m.CoOwners {
+		cm[fmt.Sprintf("$.metadata.managedFields[%d].manager", len(m.Entries)+i)] = []*yaml.Comment{
+			yaml.LineComment(" co-owner of shared fields"),
+		}
+	}
+	return cm
+}
+
+// Locates a path in a filtered object as a YAML path
+func yamlPath(o map[string]any, p fieldpath.Path) (string, error) {
+	b := (&yaml.PathBuilder{}).Root()
+	var v any = o
+	for _, e := range p {
+		switch c := v.(type) {
+		case map[string]any:
+			if e.FieldName == nil {
+				return "", fmt.Errorf("path of unexpected type: %s", p)
+			}
+			b = b.Child(*e.FieldName)
+			v = c[*e.FieldName]
+		case []any:
+			i, err := fieldpaths.FindIndex(c, e)
+			if err != nil {
+				return "", err
+			}
+			b = b.Index(uint(i))
+			v = c[i]
+		default:
+			return "", fmt.Errorf("missing field: %s", p)
+		}
+	}
+	return b.Build().String(), nil
+}
+
+// Comments a filtered object like entryComments, and its fields present in
+// desired state, as overwritten
+func objectComments(o map[string]any, m *metadata.Mutation) (yaml.CommentMap, error) {
+	cm := entryComments(m)
+	if m.Desired == nil {
+		return cm, nil
+	}
+	var err error
+	m.Desired.Leaves().Iterate(func(p fieldpath.Path) {
+		if err != nil {
+			return
+		}
+		var yp string
+		if yp, err = yamlPath(o, p); err == nil {
+			cm[yp] = []*yaml.Comment{yaml.LineComment(" overwritten by desired state")}
+		}
+	})
+	return cm, err
+}
+
 type filteredPrinter struct {
 	unstructuredPrinter
 }
 
-func (p *filteredPrinter) getFilteredObject(r runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+// Filters an object to fields selected from its mutation, without managed fields
+func (p *filteredPrinter) filterObject(
+	r runtime.Object,
+	gvk schema.GroupVersionKind,
+	sel func(*metadata.Mutation) *fieldpath.Set,
+) (*unstructured.Unstructured, *metadata.Mutation, error) {
 	o, err := p.toUnstructured(r, gvk)
 	if err != nil {
-		return nil, fmt.Errorf("cannot convert to unstructured: %s", err)
+		return nil, nil, fmt.Errorf("cannot convert to unstructured: %s", err)
 	}
 
-	c := o.DeepCopy()
+	c := p.forDisplay(o)
 	c.SetManagedFields(nil)
 
-	s, err := metadata.SolelyManuallyManagedSet(o.GetManagedFields())
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
 	if err != nil {
-		return nil, fmt.Errorf("cannot conclude field set: %s", err)
+		return nil, nil, fmt.Errorf("cannot conclude field set: %s", err)
 	}
 
-	f, err := Filter(c, s)
+	f, err := Filter(c, sel(m))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot filter resource: %s", err)
+	}
+	return f, m, nil
+}
+
+// Filters an object to mutated fields, including ones present in desired state,
+// see objectComments
+func (p *filteredPrinter) getFilteredObject(r runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, *metadata.Mutation, error) {
+	f, m, err := p.filterObject(r, gvk, func(m *metadata.Mutation) *fieldpath.Set {
+		if m.Shared != nil {
+			return m.Set.Union(m.Shared)
+		}
+		return m.Set
+	})
 	if err != nil {
-		return nil, fmt.Errorf("cannot filter resource: %s", err)
+		return nil, nil, err
 	}
+	f.SetManagedFields(displayedEntries(m))
 
-	return f, nil
+	return f, m, nil
 }
@@ -0,0 +1,118 @@
+package printers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/desired"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+)
+
+func labeledPod() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":      "test",
+			"namespace": "default",
+			"labels":    map[string]any{"app": "test", "foo": "bar"},
+		},
+	}}
+	u.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:   "kubectl-label",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{},"f:foo":{}}}}`)},
+		},
+	})
+	return u
+}
+
+func hasLabel(u *unstructured.Unstructured, k string) bool {
+	_, ok := u.GetLabels()[k]
+	return ok
+}
+
+func TestFilterObjectDesired(t *testing.T) {
+	t.Cleanup(func() { metadata.SetDesiredState(nil) })
+
+	d := desired.NewState()
+	err := d.Add(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":      "test",
+			"namespace": "default",
+			"labels":    map[string]any{"app": "test"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("cannot add desired object: %s", err)
+	}
+	metadata.SetDesiredState(d)
+
+	// namespace is not displayed, but should still be used to find desired object
+	p := filteredPrinter{unstructuredPrinter: unstructuredPrinter{withNamespace: false}}
+	f, m, err := p.getFilteredObject(labeledPod(), podGVK)
+	if err != nil {
+		t.Fatalf("cannot get filtered object: %s", err)
+	}
+	if !m.Desired.Has(fieldpath.MakePathOrDie("metadata", "labels", "app")) {
+		t.Errorf("label in desired state should be found as overwritten, got %s", m.Desired)
+	}
+	if !hasLabel(f, "app") || !hasLabel(f, "foo") {
+		t.Errorf("labels should be displayed, got %v", f.GetLabels())
+	}
+	if f.GetNamespace() != "" {
+		t.Errorf("namespace should not be displayed")
+	}
+
+	cm, err := objectComments(f.Object, m)
+	if err != nil {
+		t.Fatalf("cannot comment object: %s", err)
+	}
+	b, err := yaml.MarshalWithOptions(f.Object, yaml.WithComment(cm))
+	if err != nil {
+		t.Fatalf("cannot marshal YAML: %s", err)
+	}
+	if y := string(b); !strings.Contains(y, "app: test # overwritten by desired state\n") ||
+		!strings.Contains(y, "foo: bar\n") {
+		t.Errorf("only label in desired state should be commented as overwritten, got:\n%s", y)
+	}
+}
+
+func TestFilterObjectManualIf(t *testing.T) {
+	c, err := metadata.ParseConfig([]byte(`
+include:
+- glob: kubectl*
+manualIf: manual && object.namespace.startsWith('def')
+`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	t.Cleanup(func() { metadata.SetConfig(&metadata.Config{Include: c.Include}) })
+	metadata.SetConfig(c)
+
+	// like the scan filter, manualIf should see the namespace not displayed
+	p := filteredPrinter{unstructuredPrinter: unstructuredPrinter{withNamespace: false}}
+	f, m, err := p.getFilteredObject(labeledPod(), podGVK)
+	if err != nil {
+		t.Fatalf("cannot get filtered object: %s", err)
+	}
+	if len(m.Entries) != 1 {
+		t.Fatalf("entry should be manual by namespace, got %d entries", len(m.Entries))
+	}
+	if !hasLabel(f, "app") || !hasLabel(f, "foo") {
+		t.Errorf("labels should be displayed, got %v", f.GetLabels())
+	}
+}
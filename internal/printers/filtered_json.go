@@ -66,10 +66,14 @@ func NewFilteredJSONPrinter(withNamespace bool) (*FilteredJSONPrinter, error) {
 }
 
 func (p *FilteredJSONPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
-	o, err := p.getFilteredObject(r, gvk)
+	o, m, err := p.getFilteredObject(r, gvk)
 	if err != nil {
 		return fmt.Errorf("cannot get filtered object: %s", err)
 	}
+	// no comments in JSON, listed aside like in ReportPrinter instead
+	if m.Desired != nil && !m.Desired.Empty() {
+		o.Object["overwrittenFields"] = pathStrings(m.Desired)
+	}
 
 	b, err := json.MarshalIndent(o.Object, prefix, indent)
 	if err != nil {
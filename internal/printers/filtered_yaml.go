@@ -26,12 +26,16 @@ func NewFilteredYAMLPrinter(withNamespace bool) (*FilteredYAMLPrinter, error) {
 }
 
 func (p *FilteredYAMLPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
-	o, err := p.getFilteredObject(r, gvk)
+	o, m, err := p.getFilteredObject(r, gvk)
 	if err != nil {
 		return fmt.Errorf("cannot get filtered object: %s", err)
 	}
 
-	b, err := yaml.Marshal(o.Object)
+	cm, err := objectComments(o.Object, m)
+	if err != nil {
+		return fmt.Errorf("cannot locate fields: %s", err)
+	}
+	b, err := yaml.MarshalWithOptions(o.Object, yaml.WithComment(cm))
 	if err != nil {
 		return fmt.Errorf("cannot marshal YAML: %s", err)
 	}
@@ -29,19 +29,30 @@ func NewHighlightedYAMLPrinter(withNamespace bool) (*HighlightedYAMLPrinter, err
 	}, nil
 }
 
-type highlighter struct{}
+type highlighter struct {
+	start, end string
+}
+
+var (
+	// bold, italic
+	manualHighlighter = &highlighter{"\x1b[1;3m", "\x1b[22;23m"}
+	// italic, underline
+	sharedHighlighter = &highlighter{"\x1b[3;4m", "\x1b[23;24m"}
+	// italic, crossed-out
+	desiredHighlighter = &highlighter{"\x1b[3;9m", "\x1b[23;29m"}
+)
 
 func (v *highlighter) Visit(n ast.Node) ast.Visitor {
 	if t := n.GetToken(); t != nil {
 		if t.Value != "" {
-			t.Origin = "\x1b[1;3m" + t.Origin + "\x1b[22;23m"
+			t.Origin = v.start + t.Origin + v.end
 		}
 	}
 	return v
 }
 
-func highlight(n ast.Node) {
-	ast.Walk(&highlighter{}, n)
+func (v *highlighter) highlight(n ast.Node) {
+	ast.Walk(v, n)
 }
 
 type UnexpectedTypeError struct {
@@ -192,16 +203,16 @@ PathElementLoop:
 	return nil
 }
 
-func traverse(n ast.Node, s *fieldpath.Set) error {
+func traverse(n ast.Node, s *fieldpath.Set, h *highlighter) error {
 	if err := iterate(
 		n,
 		s.Members.All(),
 		func(kv *ast.MappingValueNode, _ fieldpath.PathElement) error {
-			highlight(kv)
+			h.highlight(kv)
 			return nil
 		},
 		func(se *ast.SequenceEntryNode, _ fieldpath.PathElement) error {
-			highlight(se)
+			h.highlight(se)
 			return nil
 		},
 	); err != nil {
@@ -212,51 +223,77 @@ func traverse(n ast.Node, s *fieldpath.Set) error {
 		n,
 		s.Children.All(),
 		func(kv *ast.MappingValueNode, p fieldpath.PathElement) error {
-			return traverse(kv.Value, s.Children.Descend(p))
+			return traverse(kv.Value, s.Children.Descend(p), h)
 		},
 		func(se *ast.SequenceEntryNode, p fieldpath.PathElement) error {
-			return traverse(se.Value, s.Children.Descend(p))
+			return traverse(se.Value, s.Children.Descend(p), h)
 		},
 	)
 }
 
-func (p *HighlightedYAMLPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
-	o, err := p.toUnstructured(r, gvk)
-	if err != nil {
-		return fmt.Errorf("cannot convert to unstructured: %s", err)
-	}
-
-	c := o.DeepCopy()
-	c.SetManagedFields(nil)
+type highlight struct {
+	set *fieldpath.Set
+	h   *highlighter
+}
 
+// Marshals an object into YAML, with fields of each set highlighted, if not nil
+func highlightYAML(o map[string]any, cm yaml.CommentMap, hs ...highlight) (string, error) {
 	// a whole round-trip make all tokens there, including spaces
-	b, err := yaml.Marshal(c.Object)
+	b, err := yaml.MarshalWithOptions(o, yaml.WithComment(cm))
 	if err != nil {
-		return err
+		return "", err
 	}
 	tokens := lexer.Tokenize(string(b))
 	f, err := parser.Parse(tokens, 0)
 	if err != nil {
-		return err
+		return "", err
 	}
 	t := f.Docs[0].Body
 
-	s, err := metadata.SolelyManuallyManagedSet(o.GetManagedFields())
+	for _, h := range hs {
+		if h.set == nil {
+			continue
+		}
+		if err := traverse(t, h.set, h.h); err != nil {
+			return "", err
+		}
+	}
+
+	pr := yamlprinter.Printer{}
+	// FIXME k of first kv in map is broken?
+	//pr.PrintErrorToken(tokens[0], true) // hack to set default colors
+	//pr.LineNumber = false // altered by PrintErrorToken
+	return pr.PrintTokens(tokens), nil
+}
+
+func (p *HighlightedYAMLPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, err := p.toUnstructured(r, gvk)
+	if err != nil {
+		return fmt.Errorf("cannot convert to unstructured: %s", err)
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
 	if err != nil {
 		return fmt.Errorf("cannot conclude field set: %s", err)
 	}
-	err = traverse(t, s)
+
+	c := p.forDisplay(o)
+	c.SetManagedFields(displayedEntries(m))
+
+	y, err := highlightYAML(
+		c.Object,
+		entryComments(m),
+		highlight{m.Sticky(), manualHighlighter},
+		highlight{m.Desired, desiredHighlighter},
+		highlight{m.Shared, sharedHighlighter},
+	)
 	if err != nil {
 		return err
 	}
 
 	// TODO wrap it with a list instead?
 	fmt.Println("---")
-	pr := yamlprinter.Printer{}
-	// FIXME k of first kv in map is broken?
-	//pr.PrintErrorToken(tokens[0], true) // hack to set default colors
-	//pr.LineNumber = false // altered by PrintErrorToken
-	if _, err := fmt.Println(pr.PrintTokens(tokens)); err != nil {
+	if _, err := fmt.Println(y); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,117 @@
+package printers
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	// private use characters, replaced with tags after escaping
+	htmlManualHighlighter  = &highlighter{"\ue001", "\ue000"}
+	htmlSharedHighlighter  = &highlighter{"\ue002", "\ue000"}
+	htmlDesiredHighlighter = &highlighter{"\ue003", "\ue000"}
+	htmlHighlightTags      = strings.NewReplacer(
+		"\ue001", `<span class="manual">`,
+		"\ue002", `<span class="shared">`,
+		"\ue003", `<span class="desired">`,
+		"\ue000", `</span>`,
+	)
+
+	htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Manually mutated resources</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; }
+td.count { text-align: right; }
+summary { cursor: pointer; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; }
+.manual { font-weight: bold; color: #b31d28; }
+.shared { text-decoration: underline; color: #735c0f; }
+.desired { text-decoration: line-through; color: #6a737d; }
+</style>
+</head>
+<body>
+<h1>Manually mutated resources</h1>
+{{- if not .Managers }}
+<p>No manually mutated resources found.</p>
+{{- else }}
+<p>In YAML, <span class="manual">fields</span> are mutated manually, <span class="shared">fields</span> are co-owned with machine managers,
+<span class="desired">fields</span> are to be overwritten by desired state.</p>
+<table>
+<tr><th>Manager</th><th>Objects</th><th>Fields</th></tr>
+{{- range .Managers }}
+<tr><td><code>{{ .Manager }}</code></td><td class="count">{{ .Objects }}</td><td class="count">{{ .Fields }}</td></tr>
+{{- end }}
+</table>
+{{- range .Namespaces }}
+<h2>{{ with .Name }}Namespace <code>{{ . }}</code>{{ else }}Cluster-scoped{{ end }}</h2>
+{{- range .Kinds }}
+<h3>{{ .Name }}</h3>
+{{- range .Objects }}
+<h4>{{ .Name }}</h4>
+<table>
+<tr><th>Manager</th><th>Fields</th></tr>
+{{- range .Managers }}
+<tr><td><code>{{ .Manager }}</code></td><td class="count">{{ .Fields }}</td></tr>
+{{- end }}
+</table>
+<details>
+<summary>Mutated fields</summary>
+<pre>{{ .YAML }}</pre>
+</details>
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+)
+
+// Prints findings as a self-contained HTML document, like MarkdownPrinter,
+// with fields highlighted by CSS like HighlightedYAMLPrinter
+type HTMLPrinter struct {
+	documentPrinter
+}
+
+func NewHTMLPrinter() (*HTMLPrinter, error) {
+	return &HTMLPrinter{
+		documentPrinter: newDocumentPrinter(),
+	}, nil
+}
+
+func (p *HTMLPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	return p.add(r, gvk, func(o map[string]any, m *metadata.Mutation) (any, error) {
+		cm, err := objectComments(o, m)
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate fields: %s", err)
+		}
+		y, err := highlightYAML(
+			o,
+			cm,
+			highlight{m.Sticky(), htmlManualHighlighter},
+			highlight{m.Desired, htmlDesiredHighlighter},
+			highlight{m.Shared, htmlSharedHighlighter},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return template.HTML(htmlHighlightTags.Replace(html.EscapeString(y))), nil
+	})
+}
+
+func (p *HTMLPrinter) Flush() error {
+	return htmlTemplate.Execute(os.Stdout, p.document())
+}
@@ -12,7 +12,29 @@ type Printer interface {
 	Flush() error
 }
 
+// Optionally implemented by printers to learn of every resource type scanned,
+// including ones without objects printed
+type ScanObserver interface {
+	// Called after objects of a resource type are printed, with count of
+	// objects listed, including ones not printed, and listing error if any
+	ResourceTypeScanned(gvk schema.GroupVersionKind, scanned int, err error)
+}
+
 var _ Printer = &TablePrinter{}
 var _ Printer = &HighlightedYAMLPrinter{}
 var _ Printer = &FilteredYAMLPrinter{}
 var _ Printer = &FilteredJSONPrinter{}
+var _ Printer = &JSONPatchPrinter{}
+var _ Printer = &KustomizePrinter{}
+var _ Printer = &DiffPrinter{}
+var _ Printer = &ResourcePrinter{}
+var _ Printer = &ReportPrinter{}
+var _ Printer = &SARIFPrinter{}
+var _ Printer = &JUnitPrinter{}
+var _ ScanObserver = &JUnitPrinter{}
+var _ Printer = &MarkdownPrinter{}
+var _ Printer = &HTMLPrinter{}
+var _ Printer = &SummaryPrinter{}
+var _ ScanObserver = &SummaryPrinter{}
+var _ Printer = &MetricsPrinter{}
+var _ ScanObserver = &MetricsPrinter{}
@@ -0,0 +1,87 @@
+package printers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/mattn/go-isatty"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+// Prints JSON lines of objects with a JSON patch (RFC 6902) removing mutated fields,
+// like in:
+//
+//	{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"app"},"patch":[{"op":"test","path":"/metadata/resourceVersion","value":"42"},{"op":"remove","path":"/spec/replicas"}]}
+//
+// Patches are good for kubectl patch --type=json. As list items are removed by
+// index, patches are guarded by resourceVersion, and fail once the object changes.
+type JSONPatchPrinter struct {
+	unstructuredPrinter
+}
+
+func NewJSONPatchPrinter(withNamespace bool) (*JSONPatchPrinter, error) {
+	return &JSONPatchPrinter{
+		unstructuredPrinter: unstructuredPrinter{
+			withNamespace: withNamespace,
+		},
+	}, nil
+}
+
+type objectPatch struct {
+	APIVersion string                      `json:"apiVersion"`
+	Kind       string                      `json:"kind"`
+	Metadata   map[string]string           `json:"metadata"`
+	Patch      []fieldpaths.PatchOperation `json:"patch"`
+}
+
+func (p *JSONPatchPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, err := p.toUnstructured(r, gvk)
+	if err != nil {
+		return fmt.Errorf("cannot convert to unstructured: %s", err)
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	// fields present in desired state will be overwritten anyway
+	ops, err := fieldpaths.RemovalPatch(m.Sticky(), o.Object)
+	if err != nil {
+		return fmt.Errorf("cannot compute patch: %s", err)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	op := objectPatch{
+		APIVersion: o.GetAPIVersion(),
+		Kind:       o.GetKind(),
+		Metadata:   map[string]string{"name": o.GetName()},
+		Patch:      fieldpaths.WithResourceVersionTest(o.GetResourceVersion(), ops),
+	}
+	if ns := o.GetNamespace(); ns != "" && p.withNamespace {
+		op.Metadata["namespace"] = ns
+	}
+
+	b, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		tokens := lexer.Tokenize(string(b))
+		fmt.Println(coloringYAMLPrinter.PrintTokens(tokens))
+	} else {
+		fmt.Println(string(b))
+	}
+	return nil
+}
+
+func (p *JSONPatchPrinter) Flush() error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package printers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+const (
+	junitPassedCase = "no mutated objects"
+	junitListCase   = "list"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Prints findings as JUnit XML, for CI
+//
+// Each resource type scanned is a test suite, with a failed test case per
+// mutated object. Types without such objects are a passing suite.
+type JUnitPrinter struct {
+	partialMetadataPrinter
+	suites map[schema.GroupVersionKind]*junitTestSuite
+	order  []schema.GroupVersionKind
+}
+
+func NewJUnitPrinter() (*JUnitPrinter, error) {
+	return &JUnitPrinter{
+		suites: map[schema.GroupVersionKind]*junitTestSuite{},
+	}, nil
+}
+
+func (p *JUnitPrinter) suite(gvk schema.GroupVersionKind) *junitTestSuite {
+	s, ok := p.suites[gvk]
+	if !ok {
+		s = &junitTestSuite{Name: gvk.GroupKind().String()}
+		p.suites[gvk] = s
+		p.order = append(p.order, gvk)
+	}
+	return s
+}
+
+func (s *junitTestSuite) add(c junitTestCase) {
+	c.ClassName = s.Name
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+	if c.Error != nil {
+		s.Errors++
+	}
+}
+
+func (p *JUnitPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, ok := r.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	byManager, err := m.GroupBy(func(e metav1.ManagedFieldsEntry) string {
+		return e.Manager
+	})
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	managers := make([]string, 0, len(byManager))
+	for mgr := range byManager {
+		managers = append(managers, mgr)
+	}
+	slices.Sort(managers)
+
+	var b strings.Builder
+	for _, mgr := range managers {
+		mm := byManager[mgr]
+		s := mm.Set
+		if mm.Shared != nil {
+			s = s.Union(mm.Shared)
+		}
+		fmt.Fprintf(&b, "%s:\n", mgr)
+		for _, f := range formatPaths(s) {
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+	}
+
+	name := o.GetName()
+	if ns := o.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+	p.suite(gvk).add(junitTestCase{
+		Name: name,
+		Failure: &junitProblem{
+			Message: "fields mutated by " + strings.Join(managers, ", "),
+			Type:    "mutated",
+			Text:    b.String(),
+		},
+	})
+	return nil
+}
+
+func (p *JUnitPrinter) ResourceTypeScanned(gvk schema.GroupVersionKind, _ int, err error) {
+	s := p.suite(gvk)
+	if err != nil {
+		s.add(junitTestCase{
+			Name: junitListCase,
+			Error: &junitProblem{
+				Message: err.Error(),
+				Type:    "list",
+			},
+		})
+	} else if s.Tests == 0 {
+		s.add(junitTestCase{Name: junitPassedCase})
+	}
+}
+
+func (p *JUnitPrinter) Flush() error {
+	res := junitTestSuites{Name: "kubectl-mutated", Suites: []*junitTestSuite{}}
+	for _, gvk := range p.order {
+		s := p.suites[gvk]
+		res.Suites = append(res.Suites, s)
+		res.Tests += s.Tests
+		res.Failures += s.Failures
+		res.Errors += s.Errors
+	}
+
+	b, err := xml.MarshalIndent(res, "", indent)
+	if err != nil {
+		return fmt.Errorf("cannot marshal XML: %s", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, xml.Header+string(b))
+	return err
+}
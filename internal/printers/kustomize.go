@@ -0,0 +1,94 @@
+package printers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+// Writes a strategic merge patch per object with mutated fields into a
+// directory, and a kustomize component applying them, to backport manual
+// edits into kustomizations
+type KustomizePrinter struct {
+	filteredPrinter
+	dir     string
+	patches []types.Patch
+}
+
+func NewKustomizePrinter(dir string, withNamespace bool) (*KustomizePrinter, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &KustomizePrinter{
+		filteredPrinter: filteredPrinter{
+			unstructuredPrinter: unstructuredPrinter{
+				withNamespace: withNamespace,
+			},
+		},
+		dir: dir,
+	}, nil
+}
+
+func (p *KustomizePrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	// including fields to be overwritten, as values are likely what to backport
+	o, _, err := p.filterObject(r, gvk, func(m *metadata.Mutation) *fieldpath.Set {
+		if m.Shared != nil {
+			return m.Set.Union(m.Shared)
+		}
+		return m.Set
+	})
+	if err != nil {
+		return fmt.Errorf("cannot get filtered object: %s", err)
+	}
+
+	b, err := yaml.Marshal(o.Object)
+	if err != nil {
+		return fmt.Errorf("cannot marshal YAML: %s", err)
+	}
+
+	parts := []string{strings.ToLower(gvk.GroupKind().String()), o.GetName()}
+	if ns := o.GetNamespace(); ns != "" {
+		parts = append([]string{ns}, parts...)
+	}
+	name := strings.Join(parts, "_") + ".yaml"
+	if err := os.WriteFile(filepath.Join(p.dir, name), b, 0o644); err != nil {
+		return err
+	}
+	fmt.Println(filepath.Join(p.dir, name))
+
+	p.patches = append(p.patches, types.Patch{Path: name})
+	return nil
+}
+
+func (p *KustomizePrinter) Flush() error {
+	k := map[string]any{
+		"apiVersion": types.ComponentVersion,
+		"kind":       types.ComponentKind,
+		"patches":    p.patches,
+	}
+	b, err := yaml.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("cannot marshal kustomization: %s", err)
+	}
+
+	name := filepath.Join(p.dir, konfig.DefaultKustomizationFileName())
+	if err := os.WriteFile(name, b, 0o644); err != nil {
+		return err
+	}
+	fmt.Println(name)
+	return nil
+}
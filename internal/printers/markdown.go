@@ -0,0 +1,73 @@
+package printers
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	markdownTemplate = template.Must(template.New("markdown").Parse(
+		`# Manually mutated resources
+{{ if not .Managers }}
+No manually mutated resources found.
+{{ else }}
+| Manager | Objects | Fields |
+| --- | ---: | ---: |
+{{ range .Managers }}| ` + "`{{ .Manager }}`" + ` | {{ .Objects }} | {{ .Fields }} |
+{{ end }}{{ range .Namespaces }}
+## {{ with .Name }}Namespace ` + "`{{ . }}`" + `{{ else }}Cluster-scoped{{ end }}
+{{ range .Kinds }}
+### {{ .Name }}
+{{ range .Objects }}
+#### {{ .Name }}
+
+| Manager | Fields |
+| --- | ---: |
+{{ range .Managers }}| ` + "`{{ .Manager }}`" + ` | {{ .Fields }} |
+{{ end }}
+<details>
+<summary>Mutated fields</summary>
+
+` + "```yaml" + `
+{{ .YAML }}` + "```" + `
+
+</details>
+{{ end }}{{ end }}{{ end }}{{ end }}`))
+)
+
+// Prints findings as a Markdown document, grouped by namespace and kind,
+// with filtered YAML in collapsible sections
+type MarkdownPrinter struct {
+	documentPrinter
+}
+
+func NewMarkdownPrinter() (*MarkdownPrinter, error) {
+	return &MarkdownPrinter{
+		documentPrinter: newDocumentPrinter(),
+	}, nil
+}
+
+func (p *MarkdownPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	return p.add(r, gvk, func(o map[string]any, m *metadata.Mutation) (any, error) {
+		cm, err := objectComments(o, m)
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate fields: %s", err)
+		}
+		b, err := yaml.MarshalWithOptions(o, yaml.WithComment(cm))
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal YAML: %s", err)
+		}
+		return string(b), nil
+	})
+}
+
+func (p *MarkdownPrinter) Flush() error {
+	return markdownTemplate.Execute(os.Stdout, p.document())
+}
@@ -0,0 +1,135 @@
+package printers
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+var (
+	mutatedFieldsDesc = prometheus.NewDesc(
+		"mutated_fields",
+		"Number of fields mutated by a manual manager, as of last scan",
+		[]string{"namespace", "group", "kind", "name", "manager"},
+		nil,
+	)
+)
+
+type mutatedFieldsSample struct {
+	gk     schema.GroupKind
+	labels []string
+	fields int
+}
+
+// Exposes findings as Prometheus metrics, as a prometheus.Collector
+//
+// Findings printed are exposed on Flush, replacing ones of previous scan,
+// so objects no longer mutated go away. Findings of resource types skipped
+// due to errors are kept from previous scan instead, to not flap alerts.
+type MetricsPrinter struct {
+	partialMetadataPrinter
+	mu      sync.Mutex
+	current []mutatedFieldsSample
+	pending []mutatedFieldsSample
+	// whether listed without errors, of resource types scanned
+	listed        map[schema.GroupKind]bool
+	skippedGroups map[string]bool
+}
+
+func NewMetricsPrinter() (*MetricsPrinter, error) {
+	return &MetricsPrinter{
+		listed:        map[schema.GroupKind]bool{},
+		skippedGroups: map[string]bool{},
+	}, nil
+}
+
+func (p *MetricsPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, ok := r.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	byManager, err := m.GroupBy(func(e metav1.ManagedFieldsEntry) string {
+		return e.Manager
+	})
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	for mgr, mm := range byManager {
+		n := mm.Set.Size()
+		if mm.Shared != nil {
+			n += mm.Shared.Size()
+		}
+		p.pending = append(p.pending, mutatedFieldsSample{
+			gk:     gvk.GroupKind(),
+			labels: []string{o.GetNamespace(), gvk.Group, gvk.Kind, o.GetName(), mgr},
+			fields: n,
+		})
+	}
+	return nil
+}
+
+func (p *MetricsPrinter) ResourceTypeScanned(gvk schema.GroupVersionKind, _ int, err error) {
+	p.listed[gvk.GroupKind()] = err == nil
+}
+
+// Records a group version failed to discover, whose resource types are unknown
+// thus all skipped, unless found in other versions
+func (p *MetricsPrinter) GroupVersionSkipped(gv schema.GroupVersion) {
+	p.skippedGroups[gv.Group] = true
+}
+
+func (p *MetricsPrinter) skipped(gk schema.GroupKind) bool {
+	listed, scanned := p.listed[gk]
+	if scanned {
+		return !listed
+	}
+	return p.skippedGroups[gk.Group]
+}
+
+func (p *MetricsPrinter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// objects printed before errors of skipped types are partial, use previous ones
+	next := slices.DeleteFunc(p.pending, func(s mutatedFieldsSample) bool {
+		return p.skipped(s.gk)
+	})
+	for _, s := range p.current {
+		if p.skipped(s.gk) {
+			next = append(next, s)
+		}
+	}
+	p.current, p.pending = next, nil
+	clear(p.listed)
+	clear(p.skippedGroups)
+	return nil
+}
+
+func (p *MetricsPrinter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mutatedFieldsDesc
+}
+
+func (p *MetricsPrinter) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.current {
+		ch <- prometheus.MustNewConstMetric(
+			mutatedFieldsDesc,
+			prometheus.GaugeValue,
+			float64(s.fields),
+			s.labels...,
+		)
+	}
+}
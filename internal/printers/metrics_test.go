@@ -0,0 +1,67 @@
+package printers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func collected(p *MetricsPrinter) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		p.Collect(ch)
+		close(ch)
+	}()
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestMetricsPrinterSkipped(t *testing.T) {
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployment := labeledPod()
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+
+	p, _ := NewMetricsPrinter()
+	if err := p.PrintObject(labeledPod(), podGVK); err != nil {
+		t.Fatalf("cannot print object: %s", err)
+	}
+	p.ResourceTypeScanned(podGVK, 1, nil)
+	if err := p.PrintObject(deployment, deploymentGVK); err != nil {
+		t.Fatalf("cannot print object: %s", err)
+	}
+	p.ResourceTypeScanned(deploymentGVK, 1, nil)
+	if err := p.Flush(); err != nil {
+		t.Fatalf("cannot flush: %s", err)
+	}
+	if n := collected(p); n != 2 {
+		t.Fatalf("expected 2 samples, got %d", n)
+	}
+
+	// objects printed before listing fails should not replace previous ones
+	if err := p.PrintObject(labeledPod(), podGVK); err != nil {
+		t.Fatalf("cannot print object: %s", err)
+	}
+	p.ResourceTypeScanned(podGVK, 1, errors.New("list failed"))
+	p.GroupVersionSkipped(deploymentGVK.GroupVersion())
+	if err := p.Flush(); err != nil {
+		t.Fatalf("cannot flush: %s", err)
+	}
+	if n := collected(p); n != 2 {
+		t.Errorf("samples of skipped types should be kept, got %d", n)
+	}
+
+	p.ResourceTypeScanned(podGVK, 0, nil)
+	p.ResourceTypeScanned(deploymentGVK, 0, nil)
+	if err := p.Flush(); err != nil {
+		t.Fatalf("cannot flush: %s", err)
+	}
+	if n := collected(p); n != 0 {
+		t.Errorf("samples of objects no longer mutated should go away, got %d", n)
+	}
+}
@@ -0,0 +1,146 @@
+package printers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+const (
+	// Bumped on incompatible changes of Report
+	ReportSchemaVersion = 1
+)
+
+// Structured findings, for parsing by other tools
+//
+// Fields may be added within a schema version, but not removed or changed.
+type Report struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Findings      []Finding `json:"findings"`
+}
+
+// Object with manually mutated fields
+type Finding struct {
+	Object  ObjectReference `json:"object"`
+	Entries []Entry         `json:"entries"`
+	// Paths of mutated fields, in the form of fieldpath.Path.String(),
+	// like .spec.containers[name="app"].image
+	Fields []string `json:"fields"`
+	// Paths of fields co-owned with machine managers, with --include-shared
+	SharedFields []string `json:"sharedFields,omitempty"`
+	// Machine managed fields entries co-owning SharedFields
+	CoOwners []Entry `json:"coOwners,omitempty"`
+	// Paths of Fields present in desired state, thus to be overwritten,
+	// with desired state
+	OverwrittenFields []string `json:"overwrittenFields,omitempty"`
+}
+
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// Managed fields entry, of a manual manager unless listed as co-owner
+type Entry struct {
+	Manager     string       `json:"manager"`
+	Operation   string       `json:"operation"`
+	Subresource string       `json:"subresource,omitempty"`
+	Time        *metav1.Time `json:"time,omitempty"`
+	// Command that likely produced the entry, if known
+	Command string `json:"command,omitempty"`
+}
+
+func toEntry(e metav1.ManagedFieldsEntry) Entry {
+	return Entry{
+		Manager:     e.Manager,
+		Operation:   string(e.Operation),
+		Subresource: e.Subresource,
+		Time:        e.Time,
+		Command:     metadata.GuessCommand(e),
+	}
+}
+
+// Prints findings as a JSON Report
+type ReportPrinter struct {
+	partialMetadataPrinter
+	report Report
+}
+
+func NewReportPrinter() (*ReportPrinter, error) {
+	return &ReportPrinter{
+		report: Report{
+			SchemaVersion: ReportSchemaVersion,
+			Findings:      []Finding{},
+		},
+	}, nil
+}
+
+func pathStrings(s *fieldpath.Set) []string {
+	if s == nil {
+		return nil
+	}
+	res := []string{}
+	s.Iterate(func(p fieldpath.Path) {
+		res = append(res, p.String())
+	})
+	return res
+}
+
+func (p *ReportPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, ok := r.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	f := Finding{
+		Object: ObjectReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  o.GetNamespace(),
+			Name:       o.GetName(),
+			UID:        string(o.GetUID()),
+		},
+		Entries: make([]Entry, len(m.Entries)),
+		Fields:  pathStrings(m.Set),
+	}
+	for i, e := range m.Entries {
+		f.Entries[i] = toEntry(e)
+	}
+	if m.Shared != nil && !m.Shared.Empty() {
+		f.SharedFields = pathStrings(m.Shared)
+		f.CoOwners = make([]Entry, len(m.CoOwners))
+		for i, e := range m.CoOwners {
+			f.CoOwners[i] = toEntry(e)
+		}
+	}
+	if m.Desired != nil && !m.Desired.Empty() {
+		f.OverwrittenFields = pathStrings(m.Desired)
+	}
+
+	p.report.Findings = append(p.report.Findings, f)
+	return nil
+}
+
+func (p *ReportPrinter) Flush() error {
+	b, err := json.MarshalIndent(p.report, "", indent)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(b))
+	return err
+}
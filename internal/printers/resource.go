@@ -0,0 +1,44 @@
+package printers
+
+import (
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crprinters "k8s.io/cli-runtime/pkg/printers"
+)
+
+// Prints objects in full with a cli-runtime printer, as a List like kubectl get,
+// for kubectl output formats like json, name or jsonpath
+type ResourcePrinter struct {
+	unstructuredPrinter
+	p    crprinters.ResourcePrinter
+	list *unstructured.UnstructuredList
+}
+
+func NewResourcePrinter(p crprinters.ResourcePrinter) (*ResourcePrinter, error) {
+	l := &unstructured.UnstructuredList{}
+	l.SetAPIVersion("v1")
+	l.SetKind("List")
+	return &ResourcePrinter{
+		unstructuredPrinter: unstructuredPrinter{
+			withNamespace: true,
+		},
+		p:    p,
+		list: l,
+	}, nil
+}
+
+func (p *ResourcePrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, err := p.toUnstructured(r, gvk)
+	if err != nil {
+		return err
+	}
+	p.list.Items = append(p.list.Items, *o)
+	return nil
+}
+
+func (p *ResourcePrinter) Flush() error {
+	return p.p.PrintObj(p.list, os.Stdout)
+}
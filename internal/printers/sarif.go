@@ -0,0 +1,229 @@
+package printers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+const (
+	sarifSchema         = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion        = "2.1.0"
+	sarifFingerprintKey = "mutatedFields/v1"
+)
+
+// Subset of SARIF 2.1.0 we produce
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// Prints findings as a SARIF log, for code scanning tools
+//
+// Each object has a result per manager category, by command guessed or
+// manager name, with a rule for each category.
+type SARIFPrinter struct {
+	partialMetadataPrinter
+	rules   map[string]sarifRule
+	results []sarifResult
+}
+
+func NewSARIFPrinter() (*SARIFPrinter, error) {
+	return &SARIFPrinter{
+		rules:   map[string]sarifRule{},
+		results: []sarifResult{},
+	}, nil
+}
+
+func managerCategory(e metav1.ManagedFieldsEntry) string {
+	if c := metadata.GuessCommand(e); c != "" {
+		return c
+	}
+	return e.Manager
+}
+
+// Rule IDs like mutated-by-kubectl-apply-server-side
+func sarifRuleID(category string) string {
+	words := strings.Fields(category)
+	for i, w := range words {
+		words[i] = strings.Trim(w, "-")
+	}
+	return "mutated-by-" + strings.Join(words, "-")
+}
+
+// Stable across runs as long as the object stays mutated by the category,
+// even with fields mutated changed, or preferred version of the resource changed
+func sarifFingerprint(gk schema.GroupKind, o metav1.Object, ruleID string) string {
+	h := sha256.New()
+	for _, s := range []string{gk.String(), o.GetNamespace(), o.GetName(), ruleID} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func formatPaths(s *fieldpath.Set) []string {
+	res := []string{}
+	s.Iterate(func(p fieldpath.Path) {
+		res = append(res, fieldpaths.Format(p))
+	})
+	return res
+}
+
+func (p *SARIFPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, ok := r.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	groups, err := m.GroupBy(managerCategory)
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	ref := gvk.GroupKind().String() + "/" + o.GetName()
+	if ns := o.GetNamespace(); ns != "" {
+		ref = ns + "/" + ref
+	}
+
+	categories := make([]string, 0, len(groups))
+	for c := range groups {
+		categories = append(categories, c)
+	}
+	slices.Sort(categories)
+
+	for _, c := range categories {
+		gm := groups[c]
+		s := gm.Set
+		if gm.Shared != nil {
+			s = s.Union(gm.Shared)
+		}
+		if s.Empty() {
+			continue
+		}
+
+		id := sarifRuleID(c)
+		if _, ok := p.rules[id]; !ok {
+			p.rules[id] = sarifRule{
+				ID:               id,
+				ShortDescription: sarifMessage{Text: "Fields manually mutated by " + c},
+			}
+		}
+
+		managers := []string{}
+		for _, e := range gm.Entries {
+			if !slices.Contains(managers, e.Manager) {
+				managers = append(managers, e.Manager)
+			}
+		}
+		text := fmt.Sprintf("%s has fields mutated by %s (%s): %s",
+			ref, c, strings.Join(managers, ", "), strings.Join(formatPaths(s), ", "))
+
+		p.results = append(p.results, sarifResult{
+			RuleID:  id,
+			Level:   "warning",
+			Message: sarifMessage{Text: text},
+			Locations: []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{
+					Name:               o.GetName(),
+					FullyQualifiedName: ref,
+					Kind:               "resource",
+				}},
+			}},
+			PartialFingerprints: map[string]string{
+				sarifFingerprintKey: sarifFingerprint(gvk.GroupKind(), o, id),
+			},
+		})
+	}
+	return nil
+}
+
+func (p *SARIFPrinter) Flush() error {
+	rules := make([]sarifRule, 0, len(p.rules))
+	for _, r := range p.rules {
+		rules = append(rules, r)
+	}
+	slices.SortFunc(rules, func(a, b sarifRule) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	l := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           "kubectl-mutated",
+					InformationURI: "https://github.com/xdavidwu/kubectl-mutated",
+					Rules:          rules,
+				},
+			},
+			Results: p.results,
+		}},
+	}
+	b, err := json.MarshalIndent(l, "", indent)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(b))
+	return err
+}
@@ -0,0 +1,153 @@
+package printers
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crprinters "k8s.io/cli-runtime/pkg/printers"
+
+	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
+)
+
+type summaryCount struct {
+	objects int
+	fields  int
+}
+
+func (c *summaryCount) add(fields int) {
+	c.objects++
+	c.fields += fields
+}
+
+// Prints tables of counts of mutated objects and fields, per namespace,
+// GroupKind and manual manager, followed by totals
+//
+// Rows are sorted by count of objects, descending, for spotting hotspots.
+type SummaryPrinter struct {
+	partialMetadataPrinter
+	o            io.Writer
+	byNamespace  map[string]*summaryCount
+	byGroupKind  map[string]*summaryCount
+	byManager    map[string]*summaryCount
+	total        summaryCount
+	scanned      int
+	scannedTypes int
+	skippedTypes int
+}
+
+func NewSummaryPrinter(o io.Writer) (*SummaryPrinter, error) {
+	return &SummaryPrinter{
+		o:           o,
+		byNamespace: map[string]*summaryCount{},
+		byGroupKind: map[string]*summaryCount{},
+		byManager:   map[string]*summaryCount{},
+	}, nil
+}
+
+func addSummaryCount(m map[string]*summaryCount, k string, fields int) {
+	c, ok := m[k]
+	if !ok {
+		c = &summaryCount{}
+		m[k] = c
+	}
+	c.add(fields)
+}
+
+func (p *SummaryPrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
+	o, ok := r.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+	byManager, err := m.GroupBy(func(e metav1.ManagedFieldsEntry) string {
+		return e.Manager
+	})
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
+	}
+
+	n := m.Set.Size()
+	if m.Shared != nil {
+		n += m.Shared.Size()
+	}
+	ns := o.GetNamespace()
+	if ns == "" {
+		ns = "<none>"
+	}
+	addSummaryCount(p.byNamespace, ns, n)
+	addSummaryCount(p.byGroupKind, gvk.GroupKind().String(), n)
+	for mgr, mm := range byManager {
+		n := mm.Set.Size()
+		if mm.Shared != nil {
+			n += mm.Shared.Size()
+		}
+		addSummaryCount(p.byManager, mgr, n)
+	}
+	p.total.add(n)
+	return nil
+}
+
+func (p *SummaryPrinter) ResourceTypeScanned(_ schema.GroupVersionKind, scanned int, err error) {
+	p.scanned += scanned
+	if err != nil {
+		p.skippedTypes++
+	} else {
+		p.scannedTypes++
+	}
+}
+
+// Each table is aligned on its own, by a new tabwriter
+func (p *SummaryPrinter) printCounts(header string, m map[string]*summaryCount) error {
+	w := crprinters.GetNewTabWriter(p.o)
+	if _, err := fmt.Fprintf(w, "%s\tOBJECTS\tFIELDS\n", header); err != nil {
+		return err
+	}
+	keys := slices.SortedFunc(maps.Keys(m), func(a, b string) int {
+		return cmp.Or(cmp.Compare(m[b].objects, m[a].objects), cmp.Compare(a, b))
+	})
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\n", k, m[k].objects, m[k].fields); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(p.o)
+	return err
+}
+
+func (p *SummaryPrinter) Flush() error {
+	for _, t := range []struct {
+		header string
+		counts map[string]*summaryCount
+	}{
+		{"NAMESPACE", p.byNamespace},
+		{"GROUPKIND", p.byGroupKind},
+		{"MANAGER", p.byManager},
+	} {
+		if err := p.printCounts(t.header, t.counts); err != nil {
+			return err
+		}
+	}
+
+	w := crprinters.GetNewTabWriter(p.o)
+	if _, err := fmt.Fprintf(
+		w,
+		"SCANNED-TYPES\tSKIPPED-TYPES\tSCANNED\tMUTATED\tFIELDS\n%d\t%d\t%d\t%d\t%d\n",
+		p.scannedTypes, p.skippedTypes, p.scanned, p.total.objects, p.total.fields,
+	); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -5,15 +5,20 @@ import (
 	"io"
 	"maps"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/liggitt/tabwriter"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
 	crprinters "k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
 
+	"github.com/xdavidwu/kubectl-mutated/internal/fieldpaths"
 	"github.com/xdavidwu/kubectl-mutated/internal/metadata"
 )
 
@@ -37,12 +42,55 @@ func formatNameColumn(o metav1.Object, gvk schema.GroupVersionKind) string {
 	)
 }
 
+const (
+	maxFieldsShown = 3
+)
+
+// Formats paths of a set for humans, truncated to maxFieldsShown
+func formatFields(s *fieldpath.Set) string {
+	paths := []string{}
+	s.Iterate(func(p fieldpath.Path) {
+		paths = append(paths, fieldpaths.Format(p))
+	})
+	if len(paths) == 0 {
+		return "<none>"
+	}
+	if len(paths) > maxFieldsShown {
+		return fmt.Sprintf("%s,+%d more", strings.Join(paths[:maxFieldsShown], ","), len(paths)-maxFieldsShown)
+	}
+	return strings.Join(paths, ",")
+}
+
+// Fetches objects as PartialObjectMetadata, for printers needing managed fields only
+type partialMetadataPrinter struct{}
+
+func (partialMetadataPrinter) ConfigureBuilder(r *resource.Builder, _ schema.GroupVersionKind) *resource.Builder {
+	return r.WithScheme(metav1Scheme, metav1.SchemeGroupVersion).
+		// TODO handle stuff without PartialObjectMetadataList support? (aggregated apis?)
+		TransformRequests(metadata.ToPartialObjectMetadataList).
+		// for disabling mapper for Flatten(),
+		// avoid attempt on PartialObjectMetadata,
+		// still perform lists
+		Local()
+}
+
+func joinKeys(m map[string]bool) string {
+	if len(m) == 0 {
+		return "<none>"
+	}
+	return strings.Join(slices.Sorted(maps.Keys(m)), ",")
+}
+
 type TablePrinter struct {
+	partialMetadataPrinter
 	w             *tabwriter.Writer
 	withNamespace bool
+	withShared    bool
+	withDesired   bool
+	wide          bool
 }
 
-func NewTablePrinter(o io.Writer, withNamespace bool) (*TablePrinter, error) {
+func NewTablePrinter(o io.Writer, withNamespace, withShared, withDesired, wide bool) (*TablePrinter, error) {
 	w := crprinters.GetNewTabWriter(o)
 
 	if withNamespace {
@@ -50,21 +98,38 @@ func NewTablePrinter(o io.Writer, withNamespace bool) (*TablePrinter, error) {
 			return nil, err
 		}
 	}
-	if _, err := fmt.Fprintln(w, "NAME\tMANAGERS\tCOUNT"); err != nil {
+	if _, err := fmt.Fprint(w, "NAME\tSUBRESOURCE\tMANAGERS\tOPERATIONS\tCOMMANDS\tCOUNT\t"); err != nil {
+		return nil, err
+	}
+	if withDesired {
+		if _, err := fmt.Fprint(w, "STICKY\tOVERWRITTEN\t"); err != nil {
+			return nil, err
+		}
+	}
+	if withShared {
+		if _, err := fmt.Fprint(w, "SHARED\tCO-OWNERS\t"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := fmt.Fprint(w, "LAST-MUTATED"); err != nil {
+		return nil, err
+	}
+	if wide {
+		if _, err := fmt.Fprint(w, "\tFIELDS"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
 		return nil, err
 	}
 
-	return &TablePrinter{w: w, withNamespace: withNamespace}, nil
-}
-
-func (TablePrinter) ConfigureBuilder(r *resource.Builder, _ schema.GroupVersionKind) *resource.Builder {
-	return r.WithScheme(metav1Scheme, metav1.SchemeGroupVersion).
-		// TODO handle stuff without PartialObjectMetadataList support? (aggregated apis?)
-		TransformRequests(metadata.ToPartialObjectMetadataList).
-		// for disabling mapper for Flatten(),
-		// avoid attempt on PartialObjectMetadata,
-		// still perform lists
-		Local()
+	return &TablePrinter{
+		w:             w,
+		withNamespace: withNamespace,
+		withShared:    withShared,
+		withDesired:   withDesired,
+		wide:          wide,
+	}, nil
 }
 
 func (t *TablePrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind) error {
@@ -73,39 +138,83 @@ func (t *TablePrinter) PrintObject(r runtime.Object, gvk schema.GroupVersionKind
 		return fmt.Errorf("unexpected type")
 	}
 
-	m := map[string]bool{}
-	for _, mf := range metadata.FindSoleManualManagers(o.GetManagedFields()) {
-		m[mf.Manager] = true
+	m, err := metadata.FindMutation(o, gvk.GroupKind())
+	if err != nil {
+		return fmt.Errorf("cannot conclude field set: %s", err)
 	}
-	managers := slices.Collect(maps.Keys(m))
-	slices.Sort(managers)
-
-	s, err := metadata.SolelyManuallyManagedSet(o.GetManagedFields())
+	bySubresource, err := m.BySubresource()
 	if err != nil {
 		return fmt.Errorf("cannot conclude field set: %s", err)
 	}
-	c := s.Size()
 
-	// TODO find a way to show fieldsV1?
+	for _, sr := range slices.Sorted(maps.Keys(bySubresource)) {
+		if err := t.printRow(o, gvk, sr, bySubresource[sr]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TablePrinter) printRow(
+	o metav1.Object,
+	gvk schema.GroupVersionKind,
+	subresource string,
+	m *metadata.Mutation,
+) error {
+	managers, operations, commands := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	var last *metav1.Time
+	for _, mf := range m.Entries {
+		if mf.Time != nil && (last == nil || last.Before(mf.Time)) {
+			last = mf.Time
+		}
+		managers[mf.Manager] = true
+		operations[string(mf.Operation)] = true
+		if c := metadata.GuessCommand(mf); c != "" {
+			commands[c] = true
+		}
+	}
+
+	if subresource == "" {
+		subresource = "<none>"
+	}
+	age := "<unknown>"
+	if last != nil {
+		age = duration.HumanDuration(time.Since(last.Time))
+	}
+
+	columns := []string{}
 	if t.withNamespace {
 		ns := o.GetNamespace()
-		var err error
-		if ns != "" {
-			_, err = fmt.Fprint(t.w, ns, "\t")
-		} else {
-			_, err = fmt.Fprint(t.w, "<none>\t")
-		}
-		if err != nil {
-			return err
+		if ns == "" {
+			ns = "<none>"
 		}
+		columns = append(columns, ns)
 	}
-	_, err = fmt.Fprintf(
-		t.w,
-		"%s\t%s\t%d\n",
+	columns = append(
+		columns,
 		formatNameColumn(o, gvk),
-		strings.Join(managers, ","),
-		c,
+		subresource,
+		joinKeys(managers),
+		joinKeys(operations),
+		joinKeys(commands),
+		strconv.Itoa(m.Set.Size()),
 	)
+	if t.withDesired {
+		columns = append(columns, strconv.Itoa(m.Sticky().Size()), strconv.Itoa(m.Desired.Size()))
+	}
+	if t.withShared {
+		coOwners := "<none>"
+		if names := m.CoOwnerNames(); len(names) != 0 {
+			coOwners = strings.Join(names, ",")
+		}
+		columns = append(columns, strconv.Itoa(m.Shared.Size()), coOwners)
+	}
+	columns = append(columns, age)
+	if t.wide {
+		columns = append(columns, formatFields(m.Set))
+	}
+
+	_, err := fmt.Fprintln(t.w, strings.Join(columns, "\t"))
 	return err
 }
 
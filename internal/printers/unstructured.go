@@ -35,8 +35,17 @@ func (p unstructuredPrinter) toUnstructured(o runtime.Object, gvk schema.GroupVe
 		u.SetGroupVersionKind(gvk)
 	}
 
+	return u, nil
+}
+
+// Copies an object for rendering, without namespace unless withNamespace
+//
+// Mutations should be found on the original object instead, as desired state
+// and --manual-if look at the namespace.
+func (p unstructuredPrinter) forDisplay(u *unstructured.Unstructured) *unstructured.Unstructured {
+	c := u.DeepCopy()
 	if !p.withNamespace {
-		delete(u.Object["metadata"].(map[string]any), "namespace")
+		delete(c.Object["metadata"].(map[string]any), "namespace")
 	}
-	return u, nil
+	return c
 }